@@ -0,0 +1,251 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/brensch/assistant/discord"
+	"github.com/bwmarrin/discordgo"
+)
+
+// openAPIVersion is the version of the OpenAPI specification the document follows.
+const openAPIVersion = "3.0.3"
+
+// openAPIDocument describes every command as an operation, with a request body schema built from
+// its options and the "discord" tags of its request struct.
+func openAPIDocument(title string, functions []discord.BotFunctionI) (map[string]any, error) {
+	paths := make(map[string]any)
+	err := addOperations(paths, nil, functions)
+	if err != nil {
+		return nil, err
+	}
+
+	errorResponse := func(description string) map[string]any {
+		return map[string]any{
+			"description": description,
+			"content":     jsonContent(schemaRef("Error")),
+		}
+	}
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   title,
+			"version": "1.0.0",
+		},
+		"security": []any{map[string]any{"bearer": []string{}}},
+		"paths":    paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+			"schemas": map[string]any{
+				"Response": responseSchema(),
+				"Error":    errorSchema(),
+			},
+			"responses": map[string]any{
+				"BadRequest":      errorResponse("The options are invalid, or the command failed in a way the caller can fix."),
+				"Unauthorized":    errorResponse("The bearer token is missing or invalid."),
+				"Forbidden":       errorResponse("The token's user isn't allowed to run the command."),
+				"TooManyRequests": errorResponse("The command is cooling down or already running."),
+				"InternalError":   errorResponse("The command failed; the error ID identifies the failure in the logs."),
+			},
+		},
+	}, nil
+}
+
+// addOperations adds an operation for each function, recursing into the subcommands of groups.
+func addOperations(paths map[string]any, parent []string, functions []discord.BotFunctionI) error {
+	for _, fn := range functions {
+		path := append(append([]string(nil), parent...), fn.GetName())
+		if group, ok := fn.(*discord.BotFunctionGroup); ok {
+			err := addOperations(paths, path, group.Functions)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		cmd, err := fn.GetCommand()
+		if err != nil {
+			return fmt.Errorf("failed to describe %s: %w", strings.Join(path, " "), err)
+		}
+		operation := map[string]any{
+			"operationId": strings.Join(path, "_"),
+			"summary":     cmd.Description,
+			"requestBody": map[string]any{
+				"required": false,
+				"content":  jsonContent(requestSchema(cmd.Options, discord.OptionTags(fn.GetRequestPrototype()))),
+			},
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "The command's response.",
+					"content":     jsonContent(schemaRef("Response")),
+				},
+				"400": responseRef("BadRequest"),
+				"401": responseRef("Unauthorized"),
+				"403": responseRef("Forbidden"),
+				"429": responseRef("TooManyRequests"),
+				"500": responseRef("InternalError"),
+			},
+		}
+		if category := fn.GetConfig().Category; category != "" {
+			operation["tags"] = []string{category}
+		}
+		paths[commandURL(path)] = map[string]any{"post": operation}
+	}
+	return nil
+}
+
+// requestSchema builds the schema of a command's JSON body: an object with a property per option.
+func requestSchema(options []*discordgo.ApplicationCommandOption, tags map[string]map[string]string) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	for _, opt := range options {
+		properties[opt.Name] = optionSchema(opt, tags[opt.Name])
+		if opt.Required {
+			required = append(required, opt.Name)
+		}
+	}
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// optionSchema builds the schema of a single option from its definition and struct tags.
+func optionSchema(opt *discordgo.ApplicationCommandOption, tags map[string]string) map[string]any {
+	schema := map[string]any{"description": opt.Description}
+	switch opt.Type {
+	case discordgo.ApplicationCommandOptionInteger:
+		schema["type"] = "integer"
+	case discordgo.ApplicationCommandOptionNumber:
+		schema["type"] = "number"
+	case discordgo.ApplicationCommandOptionBoolean:
+		schema["type"] = "boolean"
+	case discordgo.ApplicationCommandOptionString:
+		schema["type"] = "string"
+	case discordgo.ApplicationCommandOptionAttachment:
+		schema["type"] = "string"
+		schema["format"] = "uri"
+	default:
+		// Users, channels, roles and mentionables are given by ID.
+		schema["type"] = "string"
+		schema["description"] = strings.TrimSpace(opt.Description + " (" + strings.ToLower(opt.Type.String()) + " ID)")
+	}
+
+	if opt.MinValue != nil {
+		schema["minimum"] = *opt.MinValue
+	}
+	if opt.MaxValue != 0 {
+		schema["maximum"] = opt.MaxValue
+	}
+	if opt.MinLength != nil {
+		schema["minLength"] = *opt.MinLength
+	}
+	if opt.MaxLength != 0 {
+		schema["maxLength"] = opt.MaxLength
+	}
+
+	var enum []any
+	for _, choice := range opt.Choices {
+		enum = append(enum, choice.Value)
+	}
+	if values, ok := tags["enum"]; ok && len(enum) == 0 {
+		for _, v := range strings.Split(values, "|") {
+			enum = append(enum, v)
+		}
+	}
+	if len(enum) > 0 {
+		schema["enum"] = enum
+	}
+	if pattern, ok := tags["regex"]; ok {
+		schema["pattern"] = pattern
+	}
+	if def, ok := tags["default"]; ok {
+		schema["default"] = typedDefault(schema["type"], def)
+	}
+	return schema
+}
+
+// typedDefault converts a default from a struct tag to the JSON type of the option.
+func typedDefault(schemaType any, def string) any {
+	switch schemaType {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(def, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(def); err == nil {
+			return b
+		}
+	}
+	return def
+}
+
+// responseSchema describes the JSON rendering of a response.
+func responseSchema() map[string]any {
+	file := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name":         map[string]any{"type": "string"},
+			"content_type": map[string]any{"type": "string"},
+			"data":         map[string]any{"type": "string", "format": "byte"},
+		},
+	}
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"content": map[string]any{"type": "string"},
+			"embeds": map[string]any{
+				"type":        "array",
+				"description": "Discord embeds, as described in Discord's API documentation.",
+				"items":       map[string]any{"type": "object"},
+			},
+			"files":     map[string]any{"type": "array", "items": file},
+			"followups": map[string]any{"type": "array", "items": schemaRef("Response")},
+		},
+	}
+}
+
+// errorSchema describes the JSON body of a failed call.
+func errorSchema() map[string]any {
+	return map[string]any{
+		"type":     "object",
+		"required": []string{"error"},
+		"properties": map[string]any{
+			"error":    map[string]any{"type": "string"},
+			"error_id": map[string]any{"type": "string"},
+			"fields": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"field":   map[string]any{"type": "string"},
+						"message": map[string]any{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// jsonContent wraps a schema as JSON request or response content.
+func jsonContent(schema map[string]any) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+// schemaRef refers to a schema in the document's components.
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// responseRef refers to a response in the document's components.
+func responseRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/responses/" + name}
+}
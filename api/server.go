@@ -0,0 +1,247 @@
+// Package api exposes the bot's commands as JSON endpoints over HTTP, so scripts and home
+// automation can run the same commands as Discord users. Each command is served at
+// POST /commands/<command>[/<subcommand>...], and an OpenAPI document describing them is
+// served at GET /openapi.json.
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/brensch/assistant/discord"
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxBodyBytes limits the size of request bodies.
+const maxBodyBytes = 1 << 20
+
+// Token is a bearer token that may call the API, and who calls made with it run as. Commands
+// check their policies against the user, guild and roles, as they would for a Discord user.
+type Token struct {
+	// Token is the secret sent in the Authorization header as "Bearer <token>".
+	Token string
+	// UserID is the Discord user ID calls run as.
+	UserID string
+	// GuildID is the guild calls run in, or empty to run them as if in a DM.
+	GuildID string
+	// Roles are the role IDs the user is treated as having in the guild.
+	Roles []string
+}
+
+// Option configures optional settings of a Server.
+type Option func(*Server)
+
+// WithTokens adds bearer tokens that may call the API.
+func WithTokens(tokens ...Token) Option {
+	return func(s *Server) {
+		s.tokens = append(s.tokens, tokens...)
+	}
+}
+
+// WithTitle sets the title of the OpenAPI document.
+func WithTitle(title string) Option {
+	return func(s *Server) {
+		s.title = title
+	}
+}
+
+// Server serves the bot's commands as JSON endpoints. It is an http.Handler.
+type Server struct {
+	bot    *discord.Bot
+	tokens []Token
+	title  string
+	mux    *http.ServeMux
+}
+
+// NewServer creates a Server for the bot's registered functions. Every request needs one of the
+// tokens given with WithTokens; without any, every request is refused.
+func NewServer(bot *discord.Bot, opts ...Option) (*Server, error) {
+	s := &Server{
+		bot:   bot,
+		title: "Assistant API",
+		mux:   http.NewServeMux(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	doc, err := openAPIDocument(s.title, bot.Functions())
+	if err != nil {
+		return nil, err
+	}
+	s.mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, doc)
+	})
+	for _, path := range commandPaths(bot.Functions()) {
+		s.mux.HandleFunc("POST "+commandURL(path), s.handleCommand(path))
+	}
+	return s, nil
+}
+
+// ServeHTTP authenticates the request and routes it to the matching endpoint.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.authenticate(r); !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSON(w, http.StatusUnauthorized, errorBody{Error: "missing or invalid bearer token"})
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// authenticate returns the token the request was made with, if it is valid.
+func (s *Server) authenticate(r *http.Request) (Token, bool) {
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || given == "" {
+		return Token{}, false
+	}
+	for _, t := range s.tokens {
+		if t.Token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(t.Token)) == 1 {
+			return t, true
+		}
+	}
+	return Token{}, false
+}
+
+// responseBody is the JSON rendering of a response. Components are left out, as there is
+// nothing to click on over HTTP.
+type responseBody struct {
+	Content string                    `json:"content,omitempty"`
+	Embeds  []*discordgo.MessageEmbed `json:"embeds,omitempty"`
+	Files   []fileBody                `json:"files,omitempty"`
+	// Followups are any additional messages the command sent.
+	Followups []responseBody `json:"followups,omitempty"`
+}
+
+// fileBody is an attached file, with its contents base64 encoded.
+type fileBody struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type,omitempty"`
+	Data        []byte `json:"data"`
+}
+
+// errorBody describes a failed call. Invalid options are listed in Fields, and internal failures
+// only give an ID to look the full error up in the logs with.
+type errorBody struct {
+	Error   string               `json:"error"`
+	Fields  []discord.FieldError `json:"fields,omitempty"`
+	ErrorID string               `json:"error_id,omitempty"`
+}
+
+// newResponseBody renders a response as JSON.
+func newResponseBody(resp *discord.Response) responseBody {
+	body := responseBody{
+		Content: resp.Content,
+		Embeds:  resp.Embeds,
+	}
+	for _, f := range resp.Files {
+		body.Files = append(body.Files, fileBody{Name: f.Name, ContentType: f.ContentType, Data: f.Data})
+	}
+	return body
+}
+
+// handleCommand returns the handler for the command path, which decodes the options from a
+// JSON object in the body and runs the command as the token's user.
+func (s *Server) handleCommand(path []string) http.HandlerFunc {
+	command := strings.Join(path, " ")
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, _ := s.authenticate(r)
+
+		options := make(map[string]any)
+		r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+		err := json.NewDecoder(r.Body).Decode(&options)
+		// An empty body runs the command without options.
+		if err != nil && !errors.Is(err, io.EOF) {
+			writeJSON(w, http.StatusBadRequest, errorBody{Error: "body must be a JSON object of options"})
+			return
+		}
+
+		var mu sync.Mutex
+		var followups []responseBody
+		resp, err := s.bot.Invoke(r.Context(), &discord.Call{
+			Command: path,
+			Options: options,
+			User:    &discordgo.User{ID: token.UserID},
+			GuildID: token.GuildID,
+			Roles:   token.Roles,
+			Followup: func(resp *discord.Response) error {
+				mu.Lock()
+				defer mu.Unlock()
+				followups = append(followups, newResponseBody(resp))
+				return nil
+			},
+		})
+		if err != nil {
+			status, body := errorResponse(err, command)
+			writeJSON(w, status, body)
+			return
+		}
+
+		body := responseBody{}
+		if resp != nil {
+			body = newResponseBody(resp)
+		}
+		mu.Lock()
+		body.Followups = followups
+		mu.Unlock()
+		writeJSON(w, http.StatusOK, body)
+	}
+}
+
+// errorResponse logs a failed call and returns the status and body to answer it with.
+func errorResponse(err error, command string) (int, errorBody) {
+	errorID := discord.LogError("failed to execute API command", err, "command", command)
+	if errorID != "" {
+		return http.StatusInternalServerError, errorBody{Error: "internal error", ErrorID: errorID}
+	}
+
+	var verr *discord.ValidationError
+	if errors.As(err, &verr) {
+		return http.StatusBadRequest, errorBody{Error: "invalid options", Fields: verr.Fields}
+	}
+	var uerr *discord.UserError
+	errors.As(err, &uerr)
+	switch {
+	case errors.Is(err, discord.ErrPermissionDenied):
+		return http.StatusForbidden, errorBody{Error: uerr.Message}
+	case errors.Is(err, discord.ErrRateLimited):
+		return http.StatusTooManyRequests, errorBody{Error: uerr.Message}
+	}
+	return http.StatusBadRequest, errorBody{Error: uerr.Message}
+}
+
+// writeJSON writes v as the JSON body of a response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		slog.Error("failed to write API response", "error", err)
+	}
+}
+
+// commandPaths returns the path of every runnable command: functions, and each subcommand of groups.
+func commandPaths(functions []discord.BotFunctionI) [][]string {
+	var paths [][]string
+	for _, fn := range functions {
+		group, ok := fn.(*discord.BotFunctionGroup)
+		if !ok {
+			paths = append(paths, []string{fn.GetName()})
+			continue
+		}
+		for _, sub := range commandPaths(group.Functions) {
+			paths = append(paths, append([]string{fn.GetName()}, sub...))
+		}
+	}
+	return paths
+}
+
+// commandURL returns the URL path a command is served at.
+func commandURL(path []string) string {
+	return "/commands/" + strings.Join(path, "/")
+}
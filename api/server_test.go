@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brensch/assistant/discord"
+)
+
+type totalRequest struct {
+	Month string `discord:"optional,enum:jan|feb,default:jan,description:Month to total"`
+	Limit int    `discord:"optional,min:1,max:10"`
+}
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	bot, err := discord.NewLocalBot([]discord.BotFunctionI{
+		discord.NewBotFunction("total", func(req totalRequest) (*discord.Response, error) {
+			return &discord.Response{Content: "total for " + req.Month}, nil
+		}, nil, discord.WithPolicy(discord.Policy{AllowedUsers: []string{"alice"}})),
+		discord.NewBotFunctionGroup("zaps", []discord.BotFunctionI{
+			discord.NewBotFunction("fail", func(struct{}) (*discord.Response, error) {
+				return nil, errors.New("scrape failed: <html>secret</html>")
+			}, nil),
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewServer(bot, WithTokens(Token{Token: "alice-token", UserID: "alice"}, Token{Token: "bob-token", UserID: "bob"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func call(s *Server, token, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	return w
+}
+
+func TestServerCommands(t *testing.T) {
+	s := newTestServer(t)
+
+	w := call(s, "alice-token", http.MethodPost, "/commands/total", `{"month": "feb"}`)
+	var body responseBody
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &body) != nil || body.Content != "total for feb" {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body)
+	}
+
+	for _, tc := range []struct {
+		token, path, body string
+		want              int
+	}{
+		{"", "/commands/total", "", http.StatusUnauthorized},
+		{"wrong", "/commands/total", "", http.StatusUnauthorized},
+		{"bob-token", "/commands/total", "", http.StatusForbidden},
+		{"alice-token", "/commands/total", `{"limit": 50}`, http.StatusBadRequest},
+		{"alice-token", "/commands/total", `not json`, http.StatusBadRequest},
+		{"alice-token", "/commands/missing", "", http.StatusNotFound},
+		{"alice-token", "/commands/zaps/fail", "", http.StatusInternalServerError},
+	} {
+		w := call(s, tc.token, http.MethodPost, tc.path, tc.body)
+		if w.Code != tc.want {
+			t.Errorf("%s %s: expected %d, got %d %s", tc.token, tc.path, tc.want, w.Code, w.Body)
+		}
+		if strings.Contains(w.Body.String(), "secret") {
+			t.Errorf("%s: internal error details leaked: %s", tc.path, w.Body)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	s := newTestServer(t)
+	w := call(s, "alice-token", http.MethodGet, "/openapi.json", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d", w.Code)
+	}
+
+	var doc struct {
+		Paths map[string]struct {
+			Post struct {
+				RequestBody struct {
+					Content map[string]struct {
+						Schema struct {
+							Properties map[string]map[string]any
+						}
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc.Paths["/commands/zaps/fail"]; !ok {
+		t.Errorf("expected subcommands to have their own path, got %v", doc.Paths)
+	}
+	props := doc.Paths["/commands/total"].Post.RequestBody.Content["application/json"].Schema.Properties
+	month, limit := props["month"], props["limit"]
+	if month["type"] != "string" || month["default"] != "jan" || month["description"] != "Month to total" || len(month["enum"].([]any)) != 2 {
+		t.Errorf("unexpected month schema %v", month)
+	}
+	if limit["type"] != "integer" || limit["minimum"] != float64(1) || limit["maximum"] != float64(10) {
+		t.Errorf("unexpected limit schema %v", limit)
+	}
+}
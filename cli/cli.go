@@ -0,0 +1,208 @@
+// Package cli runs the bot's commands from a terminal, so they can be developed and debugged
+// without Discord. Responses, including embeds, are rendered as plain text.
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/brensch/assistant/discord"
+	"github.com/bwmarrin/discordgo"
+)
+
+// IsCommand reports whether args, without the program name, ask for the terminal transport.
+func IsCommand(args []string) bool {
+	return len(args) > 0 && (args[0] == "repl" || args[0] == "invoke")
+}
+
+// terminal runs commands for one session of the terminal transport.
+type terminal struct {
+	bot     *discord.Bot
+	user    *discordgo.User
+	guildID string
+	fileDir string
+	out     io.Writer
+}
+
+// Run runs the terminal transport. args are the program's arguments without its name:
+//
+//	repl [-user id] [-guild id] [-files dir]
+//	invoke [-user id] [-guild id] [-files dir] <command> [subcommand...] [--option value...]
+//
+// repl reads commands from in, one per line, until EOF or "exit". Commands run with the given
+// user ID and skip permission checks, as only the bot's operator has access to the terminal.
+// Attached files are listed, and saved to the -files directory if one is given.
+func Run(ctx context.Context, bot *discord.Bot, args []string, in io.Reader, out io.Writer) error {
+	if !IsCommand(args) {
+		return errors.New("usage: repl | invoke <command> [subcommand...] [--option value...]")
+	}
+
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.SetOutput(out)
+	userID := flags.String("user", "cli", "user ID to run commands as")
+	guildID := flags.String("guild", "", "guild ID to run commands in, empty to run them as if in a DM")
+	fileDir := flags.String("files", "", "directory to save attached files to")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	t := &terminal{
+		bot:     bot,
+		user:    &discordgo.User{ID: *userID, Username: *userID},
+		guildID: *guildID,
+		fileDir: *fileDir,
+		out:     out,
+	}
+	if args[0] == "invoke" {
+		return t.invoke(ctx, flags.Args())
+	}
+	return t.repl(ctx, in)
+}
+
+// repl runs each line read from in as a command, reporting errors without stopping.
+func (t *terminal) repl(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	fmt.Fprintln(t.out, `Type a command such as "help", or "exit" to quit.`)
+	for {
+		fmt.Fprint(t.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(t.out)
+			return scanner.Err()
+		}
+		args, err := splitArgs(scanner.Text())
+		if err != nil {
+			fmt.Fprintln(t.out, "error:", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "exit" || args[0] == "quit" {
+			return nil
+		}
+		if err := t.invoke(ctx, args); err != nil {
+			fmt.Fprintln(t.out, "error:", err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+// invoke runs a single command given as arguments and prints its response.
+func (t *terminal) invoke(ctx context.Context, args []string) error {
+	command, options, err := parseCall(args)
+	if err != nil {
+		return err
+	}
+	resp, err := t.bot.Invoke(ctx, &discord.Call{
+		Command: command,
+		Options: options,
+		User:    t.user,
+		GuildID: t.guildID,
+		Trusted: true,
+		Progress: func(content string) {
+			fmt.Fprintln(t.out, "...", content)
+		},
+		Followup: t.print,
+	})
+	if err != nil {
+		return err
+	}
+	return t.print(resp)
+}
+
+// print renders a response and saves its files if a directory was given.
+func (t *terminal) print(resp *discord.Response) error {
+	if resp == nil {
+		return nil
+	}
+	fmt.Fprint(t.out, Render(resp))
+	if t.fileDir == "" {
+		return nil
+	}
+	for _, f := range resp.Files {
+		path := filepath.Join(t.fileDir, filepath.Base(f.Name))
+		if err := os.WriteFile(path, f.Data, 0o644); err != nil {
+			return fmt.Errorf("failed to save %s: %w", f.Name, err)
+		}
+		fmt.Fprintln(t.out, "saved", path)
+	}
+	return nil
+}
+
+// parseCall splits arguments into the command path and its options. Arguments up to the first
+// one starting with "--" name the command and subcommands; the rest are options given as
+// "--name value" or "--name=value". An option without a value, such as "--loud", is true.
+func parseCall(args []string) ([]string, map[string]any, error) {
+	var command []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+		command = append(command, args[0])
+		args = args[1:]
+	}
+	if len(command) == 0 {
+		return nil, nil, errors.New("no command given")
+	}
+
+	options := make(map[string]any)
+	for len(args) > 0 {
+		name, ok := strings.CutPrefix(args[0], "--")
+		if !ok || name == "" {
+			return nil, nil, fmt.Errorf("expected an option like --name, got %q", args[0])
+		}
+		args = args[1:]
+		if name, value, ok := strings.Cut(name, "="); ok {
+			options[name] = value
+			continue
+		}
+		if len(args) == 0 || strings.HasPrefix(args[0], "--") {
+			options[name] = "true"
+			continue
+		}
+		options[name] = args[0]
+		args = args[1:]
+	}
+	return command, options, nil
+}
+
+// splitArgs splits a line into arguments at spaces, keeping text in single or double quotes together.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brensch/assistant/discord"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestParseCall(t *testing.T) {
+	args, err := splitArgs(`zaps export --start 2025/01/01 --note "two words" --loud`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	command, options, err := parseCall(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(command, []string{"zaps", "export"}) {
+		t.Errorf("unexpected command %v", command)
+	}
+	want := map[string]any{"start": "2025/01/01", "note": "two words", "loud": "true"}
+	if !maps.Equal(options, want) {
+		t.Errorf("expected options %v, got %v", want, options)
+	}
+
+	if _, err := splitArgs(`say "unterminated`); err == nil {
+		t.Error("expected an unterminated quote to be rejected")
+	}
+}
+
+func TestRender(t *testing.T) {
+	resp := &discord.Response{
+		Embeds: []*discordgo.MessageEmbed{{
+			Title:       "Report",
+			Description: "Total: 2\n```\nJan  2\n```",
+			Fields:      []*discordgo.MessageEmbedField{{Name: "Note", Value: "fine"}},
+		}},
+		Components: []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: "Refresh"},
+		}}},
+		Files: []*discord.File{{Name: "report.csv", Data: []byte("a,b\n")}},
+	}
+	want := "== Report ==\nTotal: 2\nJan  2\n\nNote:\n  fine\n[Refresh]\n[file report.csv, 4 bytes]\n"
+	if got := Render(resp); got != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func TestRunInvoke(t *testing.T) {
+	type echoRequest struct {
+		Text string
+	}
+	bot, err := discord.NewLocalBot([]discord.BotFunctionI{
+		discord.NewBotFunction("echo", func(req echoRequest) (*discord.Response, error) {
+			return &discord.Response{Content: req.Text}, nil
+		}, nil),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	err = Run(context.Background(), bot, []string{"invoke", "echo", "--text", "hello"}, nil, &out)
+	if err != nil || out.String() != "hello\n" {
+		t.Errorf("unexpected output %q, %v", out.String(), err)
+	}
+
+	out.Reset()
+	err = Run(context.Background(), bot, []string{"repl"}, strings.NewReader("echo --text one\necho\nexit\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "one\n") || !strings.Contains(out.String(), "error:") {
+		t.Errorf("unexpected repl output %q", out.String())
+	}
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brensch/assistant/discord"
+	"github.com/bwmarrin/discordgo"
+)
+
+// Render renders a response as plain text: its content, then each embed with its title, fields
+// and footer, then its buttons and select menus, then the names and sizes of its files.
+func Render(resp *discord.Response) string {
+	var b strings.Builder
+	if resp.Content != "" {
+		b.WriteString(plain(resp.Content) + "\n")
+	}
+	for _, embed := range resp.Embeds {
+		renderEmbed(&b, embed)
+	}
+	for _, component := range resp.Components {
+		if line := renderComponent(component); line != "" {
+			b.WriteString(line + "\n")
+		}
+	}
+	for _, f := range resp.Files {
+		fmt.Fprintf(&b, "[file %s, %d bytes]\n", f.Name, len(f.Data))
+	}
+	return b.String()
+}
+
+// renderEmbed writes an embed as a titled block of text.
+func renderEmbed(b *strings.Builder, embed *discordgo.MessageEmbed) {
+	if embed.Title != "" {
+		fmt.Fprintf(b, "== %s ==\n", embed.Title)
+	}
+	if embed.Description != "" {
+		b.WriteString(plain(embed.Description) + "\n")
+	}
+	for _, field := range embed.Fields {
+		fmt.Fprintf(b, "%s:\n", field.Name)
+		for _, line := range strings.Split(plain(field.Value), "\n") {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if embed.Footer != nil && embed.Footer.Text != "" {
+		fmt.Fprintf(b, "-- %s\n", embed.Footer.Text)
+	}
+}
+
+// renderComponent describes a row of components on one line, e.g. "[Refresh] [Share]".
+// Components can be values or pointers, depending on whether they were built or received.
+func renderComponent(component discordgo.MessageComponent) string {
+	switch c := component.(type) {
+	case discordgo.ActionsRow:
+		var parts []string
+		for _, child := range c.Components {
+			parts = append(parts, renderComponent(child))
+		}
+		return strings.Join(parts, " ")
+	case *discordgo.ActionsRow:
+		return renderComponent(*c)
+	case discordgo.Button:
+		return "[" + c.Label + "]"
+	case *discordgo.Button:
+		return renderComponent(*c)
+	case discordgo.SelectMenu:
+		var options []string
+		for _, opt := range c.Options {
+			options = append(options, opt.Label)
+		}
+		return fmt.Sprintf("<%s: %s>", c.Placeholder, strings.Join(options, " | "))
+	case *discordgo.SelectMenu:
+		return renderComponent(*c)
+	}
+	return ""
+}
+
+// plain removes the fences of Discord code blocks, as a terminal shows text in a fixed-width font anyway.
+func plain(text string) string {
+	text = strings.ReplaceAll(text, "```\n", "")
+	return strings.ReplaceAll(text, "```", "")
+}
@@ -12,18 +12,50 @@ import (
 // AppConfig holds all configuration for the application
 type AppConfig struct {
 	Discord struct {
-		AppID    string `yaml:"app_id"`
-		BotToken string `yaml:"bot_token"`
+		AppID          string `yaml:"app_id"`
+		BotToken       string `yaml:"bot_token"`
+		GlobalCommands bool   `yaml:"global_commands"`
+		BulkOverwrite  bool   `yaml:"bulk_overwrite"`
+		// Operators are the IDs of the users who can change bot-wide settings, such as notification
+		// targets with /targets. The command is left out if there are none.
+		Operators []string `yaml:"operators"`
+		// Targets are named places to send notifications. The "default" target is used when none
+		// is named, and falls back to the first text channel of every server if not configured.
+		Targets map[string]struct {
+			// Channels are channel or thread IDs to post in.
+			Channels []string `yaml:"channels"`
+			// Users are user IDs to send direct messages to.
+			Users []string `yaml:"users"`
+			// Subscribable lets users opt in to direct messages from the target with /subscriptions.
+			Subscribable bool `yaml:"subscribable"`
+		} `yaml:"targets"`
 	} `yaml:"discord"`
 
 	Dero struct {
 		Username string `yaml:"username"`
 		Password string `yaml:"password"`
+		// AllowedUsers and AllowedRoles restrict who can view zap data. Everyone can if both are empty.
+		AllowedUsers []string `yaml:"allowed_users"`
+		AllowedRoles []string `yaml:"allowed_roles"`
+		// NotificationTarget is the notification target new zap records are announced to.
+		NotificationTarget string `yaml:"notification_target"`
 	} `yaml:"dero"`
 
 	Database struct {
 		Directory string `yaml:"directory"`
 	} `yaml:"database"`
+
+	API struct {
+		// Address is the address to serve the HTTP API on, such as ":8080". The API is off if empty.
+		Address string `yaml:"address"`
+		// Tokens are the bearer tokens that may call the API, and who calls made with them run as.
+		Tokens []struct {
+			Token   string   `yaml:"token"`
+			UserID  string   `yaml:"user_id"`
+			GuildID string   `yaml:"guild_id"`
+			Roles   []string `yaml:"roles"`
+		} `yaml:"tokens"`
+	} `yaml:"api"`
 }
 
 // Global singleton config instance
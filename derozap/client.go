@@ -228,6 +228,10 @@ func (c *Client) FetchTagReads(options ...ReportOption) ([]TagRead, error) {
 			totalPages = extractTotalPages(body)
 		}
 
+		if params.Progress != nil {
+			params.Progress(currentPage, totalPages)
+		}
+
 		currentPage++
 	}
 
@@ -331,6 +335,9 @@ type ReportParams struct {
 	ReportType     string // 1051 for Tag Reads by Date.
 	ResultsPerPage int    // 50, 100, etc.
 	MinZaps        int    // Minimum number of zaps to show.
+
+	// Progress is called after each page is fetched. It is not part of the report URL.
+	Progress func(page, totalPages int)
 }
 
 // ReportOption is a function that modifies ReportParams.
@@ -375,6 +382,13 @@ func WithSortOrder(column, direction string) ReportOption {
 	}
 }
 
+// WithProgress sets a callback that is told after each page of the report is fetched.
+func WithProgress(progress func(page, totalPages int)) ReportOption {
+	return func(params *ReportParams) {
+		params.Progress = progress
+	}
+}
+
 // buildReportURL creates the URL for fetching reports with the given parameters.
 func buildReportURL(params *ReportParams) string {
 	// URL encode sort column if it contains spaces.
@@ -1,8 +1,9 @@
 package derozap
 
 import (
-	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -12,45 +13,77 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
+// refreshZapsComponent is the name of the button that refreshes a tag read breakdown.
+const refreshZapsComponent = "zaps_refresh"
+
 // DerozapRequest defines the expected inputs for the derozap command.
-// The "start" and "end" dates are optional and must be in the format yyyy/mm/dd (e.g., 2025/03/14).
+// The "start" and "end" dates are optional and are entered in the format yyyy/mm/dd (e.g., 2025/03/14).
 type DerozapRequest struct {
-	Start string `discord:"optional,description=Optional start date in yyyy/mm/dd format (e.g., 2025/03/14)"`
-	End   string `discord:"optional,description=Optional end date in yyyy/mm/dd format (e.g., 2025/03/14)"`
+	Start time.Time `discord:"optional,autocomplete,layout:2006/01/02,description:Optional start date in yyyy/mm/dd format (e.g. 2025/03/14)"`
+	End   time.Time `discord:"optional,autocomplete,layout:2006/01/02,description:Optional end date in yyyy/mm/dd format (e.g. 2025/03/14)"`
 }
 
-// convertDateFormat converts a date from "yyyy/mm/dd" to "MM/DD/YYYY" format,
-// which is the format expected by the Dero ZAP library.
-func convertDateFormat(dateStr string) (string, error) {
-	t, err := time.Parse("2006/01/02", dateStr)
+// completeZapDates suggests dates that have recorded zaps, most recent first,
+// filtered to those starting with what the user has typed so far.
+func (c *Client) completeZapDates(option string, input string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	query := `
+	SELECT DISTINCT strftime(zap_date, '%Y/%m/%d') AS day
+	FROM derozap_reads
+	WHERE strftime(zap_date, '%Y/%m/%d') LIKE ?
+	ORDER BY day DESC
+	LIMIT 25
+	`
+	rows, err := c.dbClient.Conn().Query(query, input+"%")
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to query zap dates for %s: %w", option, err)
 	}
-	return t.Format("01/02/2006"), nil
-}
+	defer rows.Close()
 
-// handleDerozapCommand processes the Discord command to fetch tag reads from Dero ZAP.
-// It returns an embed with an ASCII grid showing months down the side and years (max 5) across the top.
-func (c *Client) handleDerozapCommand(req DerozapRequest) (*discordgo.InteractionResponseData, error) {
-
-	// Prepare optional date range parameters.
-	var options []ReportOption
-	if req.Start != "" || req.End != "" {
-		if req.Start == "" || req.End == "" {
-			return nil, errors.New("both start and end dates must be provided if one is specified")
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for rows.Next() {
+		var day string
+		if err := rows.Scan(&day); err != nil {
+			return nil, fmt.Errorf("failed to scan zap date: %w", err)
 		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  day,
+			Value: day,
+		})
+	}
+	return choices, rows.Err()
+}
 
-		startFormatted, err := convertDateFormat(req.Start)
+// handleDerozapCommand processes the Discord command to fetch tag reads from Dero ZAP,
+// reporting progress to the user as each page of the report is scraped.
+func (c *Client) handleDerozapCommand(inv *discord.Invocation, req DerozapRequest) (*discord.Response, error) {
+	return c.zapBreakdown(req, func(page, totalPages int) {
+		err := inv.Progress(fmt.Sprintf("Fetched page %d of %d...", page, totalPages))
 		if err != nil {
-			return nil, fmt.Errorf("invalid start date format: %w", err)
+			slog.Warn("failed to report progress", "error", err)
 		}
+	})
+}
 
-		endFormatted, err := convertDateFormat(req.End)
-		if err != nil {
-			return nil, fmt.Errorf("invalid end date format: %w", err)
+// yearsPerPage is how many years the tag read breakdown shows side by side on each page.
+const yearsPerPage = 5
+
+// zapBreakdown fetches tag reads from Dero ZAP and returns embeds with an ASCII grid showing
+// months down the side and years across the top, paginated five years at a time with the most
+// recent years first. The optional progress callback is told after each page of the report is fetched.
+func (c *Client) zapBreakdown(req DerozapRequest, progress func(page, totalPages int)) (*discord.Response, error) {
+
+	// Prepare optional date range parameters.
+	var options []ReportOption
+	if progress != nil {
+		options = append(options, WithProgress(progress))
+	}
+	if !req.Start.IsZero() || !req.End.IsZero() {
+		if req.Start.IsZero() || req.End.IsZero() {
+			return nil, discord.UserErrorf("Both start and end dates must be provided if one is specified.")
 		}
 
-		options = append(options, WithDateRange(startFormatted, endFormatted))
+		// The Dero ZAP report expects MM/DD/YYYY dates.
+		options = append(options, WithDateRange(req.Start.Format("01/02/2006"), req.End.Format("01/02/2006")))
 	}
 
 	// Fetch tag reads from Dero ZAP.
@@ -86,11 +119,53 @@ func (c *Client) handleDerozapCommand(req DerozapRequest) (*discordgo.Interactio
 	}
 	sort.Ints(years)
 
-	// Limit to a maximum of 5 years (show the most recent 5 if available).
-	if len(years) > 5 {
-		years = years[len(years)-5:]
+	// Split the years into pages, starting from the most recent.
+	var pageYears [][]int
+	for end := len(years); end > 0; end -= yearsPerPage {
+		pageYears = append(pageYears, years[max(0, end-yearsPerPage):end])
+	}
+	if len(pageYears) == 0 {
+		pageYears = [][]int{nil}
 	}
 
+	timestamp := time.Now().Format(time.RFC3339)
+	var pages []*discord.Response
+	for _, years := range pageYears {
+		description := fmt.Sprintf("Total tag reads: %d - $%d\n\n%s", len(tagReads), len(tagReads)*15, zapGrid(years, yearMonthCounts))
+		pages = append(pages, &discord.Response{
+			Embeds: []*discordgo.MessageEmbed{{
+				Title:       "Dero ZAP Tag Reads Detailed Breakdown",
+				Description: description,
+				Color:       0x00FF00, // Green
+				Timestamp:   timestamp,
+			}},
+		})
+	}
+
+	// Attach a refresh button that re-runs the same query.
+	refreshID, err := discord.CustomID(refreshZapsComponent, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build refresh button: %w", err)
+	}
+
+	resp := discord.Paginate(pages...)
+	resp.Components = []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Refresh",
+					Style:    discordgo.SecondaryButton,
+					CustomID: refreshID,
+				},
+			},
+		},
+	}
+	return resp, nil
+}
+
+// zapGrid renders the counts for the given years as an ASCII grid inside a code block, with a
+// row per month and a column per year.
+func zapGrid(years []int, yearMonthCounts map[int]map[time.Month]int) string {
 	// Define month labels (short form).
 	monthLabels := []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
 
@@ -124,22 +199,41 @@ func (c *Client) handleDerozapCommand(req DerozapRequest) (*discordgo.Interactio
 	}
 
 	// Combine lines into an ASCII grid inside a code block.
-	asciiTable := "```\n" + strings.Join(lines, "\n") + "\n```"
-	description := fmt.Sprintf("Total tag reads: %d - $%d\n\n%s", len(tagReads), len(tagReads)*15, asciiTable)
-
-	embed := &discordgo.MessageEmbed{
-		Title:       "Dero ZAP Tag Reads Detailed Breakdown",
-		Description: description,
-		Color:       0x00FF00, // Green
-		Timestamp:   time.Now().Format(time.RFC3339),
-	}
+	return "```\n" + strings.Join(lines, "\n") + "\n```"
+}
 
-	return &discordgo.InteractionResponseData{
-		Embeds: []*discordgo.MessageEmbed{embed},
-	}, nil
+// handleRefreshZaps re-runs the tag read breakdown for the date range stored in the button.
+func (c *Client) handleRefreshZaps(req DerozapRequest, values []string) (*discord.Response, error) {
+	return c.zapBreakdown(req, nil)
+}
+
+// scrapeLimits limits scrapes of the Dero portal, whether started by the command or the refresh button.
+// Running several scrapes at once only slows each of them down and loads the portal.
+var scrapeLimits = []discord.FunctionOption{
+	discord.WithSharedLimits("derozap_scrape"),
+	discord.WithMaxInFlight(1),
+	discord.WithCooldown(discord.CooldownPerUser, 30*time.Second),
 }
 
 // DiscordFunctionRetrieveZaps returns the command handler for retrieving Dero ZAP tag reads.
-func (c *Client) DiscordFunctionRetrieveZaps() discord.BotFunctionI {
-	return discord.NewBotFunction("retreive_zaps", c.handleDerozapCommand, nil)
+// Options such as an access policy are passed through to the bot function.
+func (c *Client) DiscordFunctionRetrieveZaps(opts ...discord.FunctionOption) discord.BotFunctionI {
+	// Scraping every page of the report takes longer than Discord's three second deadline.
+	// Toll reads are personal, so they're only shown to the user unless they choose to share them.
+	opts = slices.Concat([]discord.FunctionOption{
+		discord.WithDescription("Show Dero ZAP toll reads by month"),
+		discord.WithCategory("Tolls"),
+		discord.WithExamples("/retreive_zaps", "/retreive_zaps start:2025/01/01 end:2025/03/31"),
+		discord.WithDefer(),
+		discord.WithEphemeral(),
+		discord.WithShareButton(),
+	}, scrapeLimits, opts)
+	return discord.NewBotFunctionWithContext("retreive_zaps", c.handleDerozapCommand, discord.AutocompleteFunc(c.completeZapDates), opts...)
+}
+
+// DiscordComponentRefreshZaps returns the handler for the refresh button on the tag read breakdown.
+// The button fetches the same data as the command, so it should be given the same access policy,
+// and it shares the command's cooldown and in-flight limit.
+func (c *Client) DiscordComponentRefreshZaps(opts ...discord.FunctionOption) discord.BotComponentI {
+	return discord.NewBotComponent(refreshZapsComponent, c.handleRefreshZaps, slices.Concat(scrapeLimits, opts)...)
 }
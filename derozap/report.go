@@ -0,0 +1,91 @@
+package derozap
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/brensch/assistant/discord"
+	"github.com/bwmarrin/discordgo"
+)
+
+// DiscordScheduleMonthlyReport returns a scheduled task that reports the previous month's tag reads,
+// with a CSV export of the reads attached. It reports on the reads stored by the zap check, so it
+// should run shortly after the start of each month, e.g. "0 0 9 1 * *".
+func (c *Client) DiscordScheduleMonthlyReport(cronExpression string, opts ...discord.ScheduleOption) discord.BotScheduleI {
+	return discord.NewBotSchedule("derozap_monthly_report", cronExpression, c.executeMonthlyReport, opts...)
+}
+
+// executeMonthlyReport is the handler for the monthly report schedule.
+func (c *Client) executeMonthlyReport() (*discord.Response, error) {
+	slog.Info("Executing Derozap monthly report")
+	return c.monthlyReport(previousMonth(time.Now()))
+}
+
+// previousMonth returns the start of the month before the one containing now, in UTC like the
+// report's date range. It steps back from the first of the month, as subtracting a month from
+// e.g. March 31 would overflow February and land back in March.
+func previousMonth(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+}
+
+// monthlyReport builds the report for the month containing the given time: a summary embed and
+// a CSV file with a row per tag read. Months without reads are reported too, without a file.
+func (c *Client) monthlyReport(month time.Time) (*discord.Response, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	rows, err := c.dbClient.Conn().Query(`
+	SELECT strftime(zap_date, '%Y-%m-%d'), tag_id
+	FROM derozap_reads
+	WHERE zap_date >= CAST(? AS DATE) AND zap_date < CAST(? AS DATE)
+	ORDER BY zap_date, tag_id
+	`, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag reads for %s: %w", start.Format("2006-01"), err)
+	}
+	defer rows.Close()
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"date", "tag_id"})
+	reads := 0
+	for rows.Next() {
+		var date, tagID string
+		if err := rows.Scan(&date, &tagID); err != nil {
+			return nil, fmt.Errorf("failed to scan tag read: %w", err)
+		}
+		w.Write([]string{date, tagID})
+		reads++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tag reads: %w", err)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("failed to write CSV: %w", err)
+	}
+
+	resp := &discord.Response{
+		Embeds: []*discordgo.MessageEmbed{{
+			Title:       fmt.Sprintf("Dero ZAP Report for %s", start.Format("January 2006")),
+			Description: fmt.Sprintf("Total tag reads: %d - $%d", reads, reads*15),
+			Color:       0x00FF00, // Green
+			Timestamp:   time.Now().Format(time.RFC3339),
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Automated Dero ZAP report",
+			},
+		}},
+	}
+	if reads > 0 {
+		resp.Files = []*discord.File{{
+			Name:        fmt.Sprintf("derozap-%s.csv", start.Format("2006-01")),
+			ContentType: "text/csv",
+			Data:        buf.Bytes(),
+		}}
+	}
+	return resp, nil
+}
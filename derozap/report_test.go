@@ -0,0 +1,68 @@
+package derozap
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brensch/assistant/db"
+)
+
+func TestMonthlyReport(t *testing.T) {
+	dbClient, err := db.NewClient(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbClient.Stop()
+
+	c := &Client{dbClient: dbClient}
+	if err := c.createTagReadsTable(); err != nil {
+		t.Fatal(err)
+	}
+	for _, read := range [][2]string{{"2025-02-28", "A"}, {"2025-03-01", "B"}, {"2025-03-14", "A"}, {"2025-04-01", "C"}} {
+		_, err := dbClient.Conn().Exec("INSERT INTO derozap_reads VALUES (?, ?, current_timestamp)", read[0], read[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, err := c.monthlyReport(time.Date(2025, time.March, 20, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Embeds[0].Description; got != "Total tag reads: 2 - $30" {
+		t.Errorf("unexpected summary %q", got)
+	}
+	if len(resp.Files) != 1 || resp.Files[0].Name != "derozap-2025-03.csv" {
+		t.Fatalf("unexpected files %+v", resp.Files)
+	}
+	want := "date,tag_id\n2025-03-01,B\n2025-03-14,A\n"
+	if got := string(resp.Files[0].Data); got != want {
+		t.Errorf("unexpected CSV:\n%s", got)
+	}
+
+	resp, err = c.monthlyReport(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Files) != 0 || !strings.Contains(resp.Embeds[0].Description, "0") {
+		t.Errorf("expected an empty report, got %+v", resp)
+	}
+}
+
+func TestPreviousMonth(t *testing.T) {
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2025, time.March, 31, 12, 0, 0, 0, time.UTC), "2025-02"},
+		{time.Date(2025, time.January, 1, 9, 0, 0, 0, time.UTC), "2024-12"},
+		// Local times are converted to UTC first: this is still February in UTC.
+		{time.Date(2025, time.March, 1, 9, 0, 0, 0, time.FixedZone("AEDT", 11*60*60)), "2025-01"},
+	}
+	for _, tt := range tests {
+		if got := previousMonth(tt.now).Format("2006-01"); got != tt.want {
+			t.Errorf("previousMonth(%v) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+}
@@ -9,27 +9,26 @@ import (
 	"github.com/bwmarrin/discordgo"
 )
 
-// DiscordScheduleZapCheck returns a scheduled task that periodically checks for new Dero ZAP records
-func (c *Client) DiscordScheduleZapCheck(cronExpression string) discord.BotScheduleI {
-	return discord.NewBotSchedule("derozap_check", cronExpression, c.executeZapCheck)
+// recordsPerPage is how many new records the zap check notification lists on each page.
+const recordsPerPage = 10
+
+// DiscordScheduleZapCheck returns a scheduled task that periodically checks for new Dero ZAP records.
+// Failed checks are announced with only an error ID, as fetch errors can include the portal's pages.
+// Options such as the notification target are passed through to the schedule.
+func (c *Client) DiscordScheduleZapCheck(cronExpression string, opts ...discord.ScheduleOption) discord.BotScheduleI {
+	opts = append([]discord.ScheduleOption{discord.WithFailureNotice()}, opts...)
+	return discord.NewBotSchedule("derozap_check", cronExpression, c.executeZapCheck, opts...)
 }
 
 // executeZapCheck is the handler for the scheduled task
-// It fetches tag reads, stores new ones in the database, and returns an embed notification if new records are found
-func (c *Client) executeZapCheck() (*discordgo.MessageEmbed, error) {
+// It fetches tag reads, stores new ones in the database, and returns a paginated embed notification listing them if new records are found
+func (c *Client) executeZapCheck() (*discord.Response, error) {
 	slog.Info("Executing scheduled Derozap check")
 
 	// Fetch tag reads
 	tagReads, err := c.FetchTagReads()
 	if err != nil {
-		errorMsg := fmt.Sprintf("Error fetching tag reads: %v", err)
-		slog.Error(errorMsg)
-		return &discordgo.MessageEmbed{
-			Title:       "Dero ZAP Check Error",
-			Description: errorMsg,
-			Color:       0xFF0000, // Red for errors
-			Timestamp:   time.Now().Format(time.RFC3339),
-		}, nil
+		return nil, fmt.Errorf("failed to fetch tag reads: %w", err)
 	}
 
 	if len(tagReads) == 0 {
@@ -50,33 +49,25 @@ func (c *Client) executeZapCheck() (*discordgo.MessageEmbed, error) {
 		return nil, nil
 	}
 
-	// Create description for notification
-	description := fmt.Sprintf("Found %d new record(s) (%d entries total = $%d):\n",
+	// List the new records a page at a time, so none are left out.
+	summary := fmt.Sprintf("Found %d new record(s) (%d entries total = $%d):\n",
 		len(newRecords), len(tagReads), len(tagReads)*15)
-
-	// Add details of the new records (limit to avoid overly long messages)
-	maxToShow := 5
-	if len(newRecords) < maxToShow {
-		maxToShow = len(newRecords)
-	}
-
-	for i := 0; i < maxToShow; i++ {
-		description += fmt.Sprintf("• %s: Tag ID %s\n", newRecords[i].Date, newRecords[i].TagID)
+	timestamp := time.Now().Format(time.RFC3339)
+	var pages []*discord.Response
+	for start := 0; start < len(newRecords); start += recordsPerPage {
+		description := summary
+		for _, record := range newRecords[start:min(start+recordsPerPage, len(newRecords))] {
+			description += fmt.Sprintf("• %s: Tag ID %s\n", record.Date, record.TagID)
+		}
+		pages = append(pages, &discord.Response{Embeds: []*discordgo.MessageEmbed{{
+			Title:       "New Dero ZAPs Detected",
+			Description: description,
+			Color:       0x00FF00, // Green for success
+			Timestamp:   timestamp,
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Automated Dero ZAP check",
+			},
+		}}})
 	}
-
-	// Add ellipsis if more records were found than shown
-	if len(newRecords) > maxToShow {
-		description += fmt.Sprintf("• ... and %d more\n", len(newRecords)-maxToShow)
-	}
-
-	// Create and return the notification embed
-	return &discordgo.MessageEmbed{
-		Title:       "New Dero ZAPs Detected",
-		Description: description,
-		Color:       0x00FF00, // Green for success
-		Timestamp:   time.Now().Format(time.RFC3339),
-		Footer: &discordgo.MessageEmbedFooter{
-			Text: "Automated Dero ZAP check",
-		},
-	}, nil
+	return discord.Paginate(pages...), nil
 }
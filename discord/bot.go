@@ -1,156 +1,193 @@
 package discord
 
 import (
-	"fmt"
+	"context"
+	"slices"
 	"strings"
+	"sync"
 
 	"log/slog"
 
 	"github.com/bwmarrin/discordgo"
 )
 
-// Bot encapsulates the discordgo session, configuration, registered functions, and schedules.
+// Bot encapsulates the Discord session, configuration, registered functions, and schedules.
 type Bot struct {
-	session         *discordgo.Session
+	session         Session
 	config          BotConfig
 	functions       []BotFunctionI
+	components      []BotComponentI
+	modals          []BotModalI
+	contextMenus    []BotContextMenuI
+	messageHandlers []BotMessageHandlerI
 	schedules       []BotScheduleI
 	scheduleManager *scheduleManager
+	// commands is the set of application commands built from functions.
+	commands []*discordgo.ApplicationCommand
+	// guilds holds per-guild state for every guild the bot has been set up in, guarded by mu.
+	guilds map[string]*guildState
+	mu     sync.Mutex
+	// targets holds the named notification targets, guarded by mu.
+	targets map[string]Target
+	// subscriptions stores users' opt-ins to targets, if enabled with WithSubscriptions.
+	subscriptions *subscriptionStore
+	// limits enforces the cooldowns and in-flight limits of functions.
+	limits *limiter
+	// middleware wraps every command, guarded by mu.
+	middleware []Middleware
+	// pages keeps the pages of paginated responses for their navigation buttons.
+	pages *pageStore
+	// help is the built-in /help command, or nil if a function provides its own.
+	help BotFunctionI
+	// ctx is the parent of every invocation's context and is cancelled when the bot closes.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// BotOption is a function that configures optional features of a Bot.
+type BotOption func(*Bot)
+
+// WithComponents registers handlers for message components (buttons and select menus).
+func WithComponents(components ...BotComponentI) BotOption {
+	return func(b *Bot) {
+		b.components = append(b.components, components...)
+	}
 }
 
 // BotConfig contains configuration for the bot.
 type BotConfig struct {
 	AppID    string
 	BotToken string
+	// GlobalCommands registers commands once for the whole application instead of in each guild.
+	// Global commands also work in DMs, but changes to them can take a while to reach clients.
+	GlobalCommands bool
+	// BulkOverwrite replaces the registered commands with a single request instead of creating,
+	// editing and deleting only the commands that changed.
+	BulkOverwrite bool
 }
 
-// NewBot creates a new Bot instance, re-registers each command function on a per-guild basis,
-// and sends an online message listing all available commands to each guild.
+// WithModals registers modal forms so their submissions are routed back to them.
+func WithModals(modals ...BotModalI) BotOption {
+	return func(b *Bot) {
+		b.modals = append(b.modals, modals...)
+	}
+}
+
+// NewBot creates a new Bot instance, registers each command function either per guild or globally,
+// changing only the commands that differ from what Discord already has, and sends an online message
+// listing all available commands to each guild, including guilds joined while the bot is running.
 // It also initializes scheduled tasks based on the provided cron expressions.
-func NewBot(cfg BotConfig, functions []BotFunctionI, schedules []BotScheduleI) (*Bot, error) {
+// Additional features such as component handlers are enabled through opts.
+func NewBot(cfg BotConfig, functions []BotFunctionI, schedules []BotScheduleI, opts ...BotOption) (*Bot, error) {
 	// Create a new Discord session using the provided bot token.
-	dg, err := discordgo.New("Bot " + cfg.BotToken)
+	session, err := NewSession(cfg.BotToken)
 	if err != nil {
 		return nil, err
 	}
+	return NewBotWithSession(session, cfg, functions, schedules, opts...)
+}
 
-	// Set necessary intents.
-	dg.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsMessageContent
-
-	bot := &Bot{
-		session:   dg,
-		config:    cfg,
-		functions: functions,
-		schedules: schedules,
+// NewBotWithSession creates a Bot as NewBot does, but on the given session, such as a fake from the
+// discordtest package. The session is opened and closed by the bot.
+func NewBotWithSession(session Session, cfg BotConfig, functions []BotFunctionI, schedules []BotScheduleI, opts ...BotOption) (*Bot, error) {
+	bot, err := newBot(session, cfg, functions, schedules, opts...)
+	if err != nil {
+		return nil, err
 	}
 
 	// Register event handlers.
-	dg.AddHandler(bot.onMessageCreate)
-	dg.AddHandler(bot.onInteractionCreate)
+	session.AddHandler(bot.onMessageCreate)
+	session.AddHandler(bot.onInteractionCreate)
+	session.AddHandler(bot.onGuildCreate)
+	session.AddHandler(bot.onGuildDelete)
+	session.AddHandler(bot.onChannelCreate)
+	session.AddHandler(bot.onThreadCreate)
 
 	// Open the websocket connection.
-	if err := dg.Open(); err != nil {
+	if err := session.Open(); err != nil {
 		return nil, err
 	}
 
-	// Build a comma-separated list of command names for the online message.
-	var availableCommands []string
-	for _, fn := range functions {
-		availableCommands = append(availableCommands, fn.GetName())
-	}
-
-	// Add schedule names to the message
-	var activeSchedules []string
-	for _, schedule := range schedules {
-		activeSchedules = append(activeSchedules, fmt.Sprintf("%s (%s)", schedule.GetName(), schedule.GetCronExpression()))
-	}
-
-	commandsMessage := fmt.Sprintf("Assistant online. Available commands: %s", strings.Join(availableCommands, ", "))
-	if len(activeSchedules) > 0 {
-		commandsMessage += fmt.Sprintf("\nActive schedules: %s", strings.Join(activeSchedules, ", "))
-	}
-
-	// For each guild, delete all existing bot commands and register new ones.
-	for _, guild := range dg.State.Guilds {
-		// Retrieve the existing commands for the guild.
-		existingCommands, err := dg.ApplicationCommands(cfg.AppID, guild.ID)
+	// Global commands are registered once; per-guild commands and the online message are set up
+	// by onGuildCreate as Discord reports each guild, including ones joined later.
+	if cfg.GlobalCommands {
+		err = bot.syncCommands("", bot.commands)
 		if err != nil {
-			slog.Error("failed to get commands for guild", "guild", guild.ID, "error", err)
-			continue
-		}
-		// Delete each existing command.
-		for _, cmd := range existingCommands {
-			err := dg.ApplicationCommandDelete(cfg.AppID, guild.ID, cmd.ID)
-			if err != nil {
-				slog.Error("failed to delete command", "guild", guild.ID, "command", cmd.Name, "error", err)
-			} else {
-				slog.Debug("deleted command", "guild", guild.ID, "command", cmd.Name)
-			}
-		}
-		// Register each new command for this guild.
-		for _, fn := range functions {
-			options, err := structToCommandOptions(fn.GetRequestPrototype())
-			if err != nil {
-				slog.Error("failed to generate command options", "command", fn.GetName(), "error", err)
-				return nil, err
-			}
-			slog.Debug("initialising function", "name", fn.GetName(), "options", options)
-			newCmd := &discordgo.ApplicationCommand{
-				Name:        fn.GetName(),
-				Description: "Auto-generated command for " + fn.GetName(),
-				Options:     options,
-			}
-			_, err = dg.ApplicationCommandCreate(cfg.AppID, guild.ID, newCmd)
-			if err != nil {
-				slog.Error("failed to create guild slash command", "guild", guild.ID, "command", fn.GetName(), "error", err)
-				return nil, err
-			}
+			slog.Error("failed to register global commands", "error", err)
+			return nil, err
 		}
 	}
 
-	// Send the online message to every guild the bot is in.
-	for _, guild := range dg.State.Guilds {
-		// Retrieve the guild channels.
-		channels, err := dg.GuildChannels(guild.ID)
+	// Initialize and start the schedule manager if there are schedules
+	if len(schedules) > 0 {
+		bot.scheduleManager = newScheduleManager(bot, schedules)
+		err = bot.scheduleManager.start()
 		if err != nil {
-			slog.Error("failed to get guild channels", "guild", guild.ID, "error", err)
-			continue // Skip sending the message if channels cannot be fetched.
+			slog.Error("failed to start schedule manager", "error", err)
+			return nil, err
 		}
+	}
 
-		// Find the first text channel.
-		var targetChannel string
-		for _, channel := range channels {
-			if channel.Type == discordgo.ChannelTypeGuildText {
-				targetChannel = channel.ID
-				break
-			}
-		}
+	return bot, nil
+}
 
-		// If a text channel is found, send the online message.
-		if targetChannel != "" {
-			_, err = dg.ChannelMessageSend(targetChannel, commandsMessage)
-			if err != nil {
-				slog.Error("failed to send online message", "guild", guild.ID, "error", err)
-			}
-		}
+// NewLocalBot creates a Bot that isn't connected to Discord, for running its commands through other
+// transports with Invoke, such as a terminal. Schedules don't run and notifications can't be sent.
+func NewLocalBot(functions []BotFunctionI, opts ...BotOption) (*Bot, error) {
+	return newBot(nil, BotConfig{}, functions, nil, opts...)
+}
+
+// newBot builds a Bot around session, applying opts and building the command set, without connecting.
+func newBot(session Session, cfg BotConfig, functions []BotFunctionI, schedules []BotScheduleI, opts ...BotOption) (*Bot, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	bot := &Bot{
+		session:   session,
+		config:    cfg,
+		functions: functions,
+		schedules: schedules,
+		guilds:    make(map[string]*guildState),
+		targets:   make(map[string]Target),
+		limits:    newLimiter(),
+		pages:     newPageStore(),
+		ctx:       ctx,
+		cancel:    cancel,
 	}
 
-	// Initialize and start the schedule manager if there are schedules
-	if len(schedules) > 0 {
-		bot.scheduleManager = newScheduleManager(bot, schedules)
-		err = bot.scheduleManager.start()
+	// Apply any optional features.
+	for _, opt := range opts {
+		opt(bot)
+	}
+
+	// Add the built-in /help unless a function already provides one.
+	if bot.findFunction(helpCommand) == nil {
+		bot.help = bot.helpFunction()
+		bot.functions = append(slices.Clip(bot.functions), bot.help)
+	}
+
+	if bot.subscriptions != nil {
+		err := bot.subscriptions.init()
 		if err != nil {
-			slog.Error("failed to start schedule manager", "error", err)
 			return nil, err
 		}
 	}
 
+	// Build the desired command set before connecting, as guilds start arriving as soon as the
+	// websocket is open.
+	commands, err := bot.buildCommands()
+	if err != nil {
+		return nil, err
+	}
+	bot.commands = commands
+
 	return bot, nil
 }
 
-// onMessageCreate logs every message the bot sees (ignoring its own).
-func (b *Bot) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
-	if m.Author.ID == s.State.User.ID {
+// onMessageCreate logs every message the bot sees (ignoring its own) and passes messages from
+// users to the registered message handlers.
+func (b *Bot) onMessageCreate(_ *discordgo.Session, m *discordgo.MessageCreate) {
+	defer logPanic("message")
+
+	if m.Author.ID == b.session.BotUserID() {
 		return
 	}
 
@@ -160,85 +197,295 @@ func (b *Bot) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate)
 		"channel_id", m.ChannelID,
 		"content", m.Content,
 		"attachments", len(m.Attachments))
+
+	// Ignore other bots so they can't trigger each other in a loop.
+	if m.Author.Bot {
+		return
+	}
+	b.handleMessage(m.Message)
 }
 
-// onInteractionCreate routes interactions to the correct BotFunction based on the command name.
-func (b *Bot) onInteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	cmdData := i.ApplicationCommandData()
+// onInteractionCreate routes interactions to the correct handler based on the interaction type.
+func (b *Bot) onInteractionCreate(_ *discordgo.Session, i *discordgo.InteractionCreate) {
+	defer logPanic("interaction")
 
-	slog.Debug("received interaction", "cmd", cmdData)
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		b.handleCommand(i)
+	case discordgo.InteractionApplicationCommandAutocomplete:
+		b.handleAutocomplete(i)
+	case discordgo.InteractionMessageComponent:
+		b.handleComponent(i)
+	case discordgo.InteractionModalSubmit:
+		b.handleModalSubmit(i)
+	default:
+		slog.Warn("received unsupported interaction type", "type", i.Type.String())
+	}
+}
 
-	// Find the registered function with a matching name.
-	var fn BotFunctionI
+// Functions returns the registered functions, including built-in ones such as /help.
+func (b *Bot) Functions() []BotFunctionI {
+	return slices.Clone(b.functions)
+}
+
+// findFunction returns the registered function with the given name, or nil if there is none.
+func (b *Bot) findFunction(name string) BotFunctionI {
 	for _, f := range b.functions {
-		if f.GetName() == cmdData.Name {
-			fn = f
-			break
+		if f.GetName() == name {
+			return f
 		}
 	}
-	if fn == nil {
-		slog.Warn("received unknown command", "command", cmdData.Name)
-		// Optionally send an error embed for an unknown command.
-		errorEmbed := &discordgo.MessageEmbed{
-			Title:       "Error",
-			Description: "Unknown command: " + cmdData.Name,
-			Color:       0xFF0000,
+	return nil
+}
+
+// findComponent returns the registered component with the given name, or nil if there is none.
+func (b *Bot) findComponent(name string) BotComponentI {
+	for _, c := range b.components {
+		if c.GetName() == name {
+			return c
 		}
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+	}
+	return nil
+}
+
+// handleComponent routes a button click or select menu choice to the component named in its custom ID.
+func (b *Bot) handleComponent(i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+
+	slog.Debug("received component interaction", "custom_id", data.CustomID)
+
+	name, _, _ := strings.Cut(data.CustomID, customIDSeparator)
+	switch name {
+	case shareComponent:
+		b.handleShare(i)
+		return
+	case pageComponent:
+		b.handlePage(i)
+		return
+	case pageJumpComponent:
+		b.handlePageJump(i)
+		return
+	}
+	component := b.findComponent(name)
+	if component == nil {
+		slog.Warn("received unknown component", "custom_id", data.CustomID)
+		b.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Embeds: []*discordgo.MessageEmbed{errorEmbed},
-			},
+			Data: errorResponse("This button is no longer supported."),
 		})
 		return
 	}
 
-	// Execute the function's handler using the interaction data.
-	respData, err := fn.HandleInteraction(&cmdData)
+	responseType := discordgo.InteractionResponseChannelMessageWithSource
+	if component.UpdatesMessage() {
+		responseType = discordgo.InteractionResponseUpdateMessage
+	}
+	r := newResponder(b.session, i.Interaction, responseType)
+	r.autoDefer()
+	inv, cancel := newInvocation(b.ctx, r)
+	defer cancel()
+
+	resp, err := recovered(func() (*Response, error) {
+		resp, err := b.runComponent(inv, component, &data)
+		if err != nil {
+			return nil, err
+		}
+		// Responses replacing a message that could be shared can be shared too.
+		if component.UpdatesMessage() && hasShareButton(i.Message) {
+			addShareButton(resp)
+		}
+		return b.renderPages(resp, component)
+	})
+	if err != nil {
+		// Errors go out as a new message so the original message is left intact.
+		err = r.respondError(reportError("failed to execute component", err, "component", name))
+		if err != nil {
+			slog.Error("failed to send component error", "component", name, "error", err)
+		}
+		return
+	}
+
+	err = r.respond(resp.interactionData(), resp.opensModal())
 	if err != nil {
-		slog.Error("failed to execute command", "command", fn.GetName(), "error", err.Error())
-		errorEmbed := &discordgo.MessageEmbed{
-			Title:       "Error",
-			Description: fmt.Sprintf("```%v```", err),
-			Color:       0xFF0000,
+		slog.Error("failed to respond to component", "component", name, "error", err)
+	}
+}
+
+// findModal returns the registered modal with the given name, or nil if there is none.
+func (b *Bot) findModal(name string) BotModalI {
+	for _, m := range b.modals {
+		if m.GetName() == name {
+			return m
 		}
-		// Respond with the error embed.
-		s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+	}
+	return nil
+}
+
+// handleModalSubmit routes a submitted modal to the modal named in its custom ID.
+func (b *Bot) handleModalSubmit(i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+
+	slog.Debug("received modal submission", "custom_id", data.CustomID)
+
+	name, _, _ := strings.Cut(data.CustomID, customIDSeparator)
+	if name == pageJumpComponent {
+		b.handlePageJumpSubmit(i)
+		return
+	}
+
+	r := newResponder(b.session, i.Interaction, discordgo.InteractionResponseChannelMessageWithSource)
+	r.autoDefer()
+	inv, cancel := newInvocation(b.ctx, r)
+	defer cancel()
+
+	modal := b.findModal(name)
+
+	var resp *Response
+	var err error
+	if modal == nil {
+		err = UserErrorf("This form is no longer supported.")
+	} else {
+		resp, err = recovered(func() (*Response, error) {
+			resp, err := b.runModal(inv, modal, &data)
+			if err != nil {
+				return nil, err
+			}
+			return b.renderPages(resp, modal)
+		})
+	}
+	respData, opensModal := resp.interactionData(), resp.opensModal()
+	if err != nil {
+		respData, opensModal = reportError("failed to handle modal submission", err, "modal", name), false
+	}
+
+	err = r.respond(respData, opensModal)
+	if err != nil {
+		slog.Error("failed to respond to modal submission", "modal", name, "error", err)
+	}
+}
+
+// handleAutocomplete answers an autocomplete interaction with the suggestions from the matching BotFunction.
+func (b *Bot) handleAutocomplete(i *discordgo.InteractionCreate) {
+	cmdData := i.ApplicationCommandData()
+
+	slog.Debug("received autocomplete", "cmd", cmdData.Name)
+
+	fn := b.findFunction(cmdData.Name)
+	if fn == nil {
+		slog.Warn("received autocomplete for unknown command", "command", cmdData.Name)
+		return
+	}
+
+	// Suggestions can reveal data, so they are subject to the same policies as the command.
+	inv, cancel := newInvocation(b.ctx, newResponder(b.session, i.Interaction, discordgo.InteractionApplicationCommandAutocompleteResult))
+	defer cancel()
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	var err error
+	if b.authorize(inv, commandChain(fn, &cmdData)) {
+		choices, err = recovered(func() ([]*discordgo.ApplicationCommandOptionChoice, error) {
+			if fn == b.help {
+				// Help suggests command names, which are hidden from those who can't run the commands.
+				return b.completeCommands(inv, &cmdData), nil
+			}
+			return fn.HandleAutocomplete(&cmdData)
+		})
+		if err != nil {
+			LogError("failed to autocomplete command", err, "command", fn.GetName())
+			// Respond with no suggestions so the client stops waiting.
+			choices = nil
+		}
+	}
+
+	err = b.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+	if err != nil {
+		slog.Error("failed to respond to autocomplete", "command", fn.GetName(), "error", err)
+	}
+}
+
+// handleCommand routes a slash command or context menu interaction to the correct BotFunction based
+// on the command's type and name, running its handler through the middleware chain built by
+// commandHandler. The response is deferred up front for functions that ask for it, and automatically
+// for any handler that hasn't returned after a couple of seconds, in which case the deferred response
+// is edited once the handler finishes.
+func (b *Bot) handleCommand(i *discordgo.InteractionCreate) {
+	cmdData := i.ApplicationCommandData()
+
+	slog.Debug("received interaction", "cmd", cmdData)
+
+	// Find the registered function or context menu command with a matching name.
+	fn := b.findCommand(&cmdData)
+	if fn == nil {
+		slog.Warn("received unknown command", "command", cmdData.Name)
+		b.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
-			Data: &discordgo.InteractionResponseData{
-				Embeds: []*discordgo.MessageEmbed{errorEmbed},
-			},
+			Data: errorResponse("Unknown command: " + cmdData.Name),
 		})
 		return
 	}
 
+	r := newResponder(b.session, i.Interaction, discordgo.InteractionResponseChannelMessageWithSource)
+	inv, cancel := newInvocation(b.ctx, r)
+	defer cancel()
+	inv.chain = commandChain(fn, &cmdData)
+	inv.Command = commandName(inv.chain)
+	inv.Data = &cmdData
+
+	// Run the function's handler wrapped in its middleware, which includes the access checks.
+	resp, err := b.commandHandler(inv.chain)(inv)
+	if err == nil {
+		guards := make([]guarded, len(inv.chain))
+		for n, fn := range inv.chain {
+			guards[n] = fn
+		}
+		resp, err = recovered(func() (*Response, error) { return b.renderPages(resp, guards...) })
+	}
+	respData, opensModal := resp.interactionData(), resp.opensModal()
+	if err != nil {
+		respData, opensModal = reportError("failed to execute command", err, "command", inv.Command), false
+	}
+
 	// Respond to the interaction using the returned response data.
-	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: respData,
-	})
+	err = r.respond(respData, opensModal)
 	if err != nil {
-		slog.Error("failed to respond to command", "command", fn.GetName(), "error", err)
-		errorEmbed := &discordgo.MessageEmbed{
-			Title:       "Error",
-			Description: fmt.Sprintf("```%v```", err),
-			Color:       0xFF0000,
+		// Attempt to send a follow-up error message if the response fails.
+		errData := reportError("failed to respond to command", err, "command", fn.GetName())
+		_, err = b.session.FollowupMessageCreate(i.Interaction, true, webhookParams(errData))
+		if err != nil {
+			slog.Error("failed to send follow-up error", "command", fn.GetName(), "error", err)
+		}
+	}
+}
+
+// inChain reports whether setting is on in the config of any function in the command chain.
+func inChain(chain []BotFunctionI, setting func(cfg FunctionConfig) bool) bool {
+	for _, fn := range chain {
+		if setting(fn.GetConfig()) {
+			return true
 		}
-		// Attempt to send a follow-up error message if the initial response fails.
-		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Embeds: []*discordgo.MessageEmbed{errorEmbed},
-		})
 	}
+	return false
 }
 
 // Close gracefully closes the Discord session and stops the schedule manager.
 func (b *Bot) Close() error {
 	slog.Info("shutting down bot")
 
+	// Cancel any in-flight invocations.
+	b.cancel()
+
 	// Stop the schedule manager if it was initialized
 	if b.scheduleManager != nil {
 		b.scheduleManager.stop()
 	}
 
+	if b.session == nil {
+		return nil
+	}
 	return b.session.Close()
 }
@@ -0,0 +1,250 @@
+package discord
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/brensch/assistant/discord/discordtest"
+	"github.com/bwmarrin/discordgo"
+)
+
+var _ Session = (*discordtest.Session)(nil)
+
+type helloRequest struct {
+	Name string `discord:"description:Who to say hello to"`
+}
+
+// newTestBot starts a bot on a fake session that is already in one guild with a voice channel
+// followed by a text channel.
+func newTestBot(t *testing.T, functions []BotFunctionI, opts ...BotOption) (*Bot, *discordtest.Session) {
+	t.Helper()
+	session := discordtest.NewSession("bot")
+	session.AddGuild(&discordgo.Guild{ID: "g1", Name: "Home", OwnerID: "owner"},
+		&discordgo.Channel{ID: "voice", Type: discordgo.ChannelTypeGuildVoice},
+		&discordgo.Channel{ID: "general", Type: discordgo.ChannelTypeGuildText},
+	)
+	bot, err := NewBotWithSession(session, BotConfig{AppID: "app"}, functions, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bot.Close() })
+	return bot, session
+}
+
+// commandInteraction builds a slash command interaction from a guild member.
+func commandInteraction(userID string, data discordgo.ApplicationCommandInteractionData) *discordgo.Interaction {
+	return &discordgo.Interaction{
+		Type:      discordgo.InteractionApplicationCommand,
+		GuildID:   "g1",
+		ChannelID: "general",
+		Member:    &discordgo.Member{User: &discordgo.User{ID: userID}},
+		Data:      data,
+	}
+}
+
+func TestBotGuildSetup(t *testing.T) {
+	greet := NewBotFunction("greet", func(req helloRequest) (*Response, error) { return nil, nil }, nil)
+	_, session := newTestBot(t, []BotFunctionI{greet})
+
+	var names []string
+	for _, cmd := range session.Commands("g1") {
+		names = append(names, cmd.Name)
+	}
+	if strings.Join(names, ",") != "greet,help" {
+		t.Errorf("expected greet and help to be registered in the guild, got %v", names)
+	}
+	messages := session.Messages()
+	if len(messages) != 1 || messages[0].ChannelID != "general" || !strings.Contains(messages[0].Content, "greet") {
+		t.Fatalf("expected an online message in the first text channel, got %+v", messages)
+	}
+
+	// Guilds joined later are set up too.
+	session.AddGuild(&discordgo.Guild{ID: "g2"}, &discordgo.Channel{ID: "lobby", Type: discordgo.ChannelTypeGuildText})
+	if len(session.Commands("g2")) != 2 || len(session.Messages()) != 2 {
+		t.Errorf("expected the new guild to be set up, got %v commands", len(session.Commands("g2")))
+	}
+}
+
+func TestBotGuildLeave(t *testing.T) {
+	bot, session := newTestBot(t, nil, WithTargets(map[string]Target{
+		"alerts":  {Channels: []string{"general", "elsewhere", "later", "thread"}},
+		"default": {Channels: []string{"general"}},
+	}))
+	session.AddGuild(&discordgo.Guild{ID: "g2"}, &discordgo.Channel{ID: "lobby", Type: discordgo.ChannelTypeGuildText})
+	// Channels and threads created after the bot joined are taken out too.
+	session.AddChannel("g1", &discordgo.Channel{ID: "later", Type: discordgo.ChannelTypeGuildText})
+	session.AddChannel("g1", &discordgo.Channel{ID: "thread", Type: discordgo.ChannelTypeGuildPublicThread})
+
+	session.RemoveGuild("g1")
+	bot.mu.Lock()
+	_, known := bot.guilds["g1"]
+	bot.mu.Unlock()
+	if known {
+		t.Error("expected the guild to be forgotten")
+	}
+	// The departed guild's channels are taken out of targets, and targets left empty are removed.
+	targets := bot.Targets()
+	if alerts := targets["alerts"]; len(alerts.Channels) != 1 || alerts.Channels[0] != "elsewhere" {
+		t.Errorf("expected only the other channel to remain, got %v", alerts.Channels)
+	}
+	if _, ok := targets[DefaultTarget]; ok {
+		t.Error("expected the emptied default target to be removed")
+	}
+
+	// Notifications fall back to the remaining guild.
+	bot.SendEmbed(&discordgo.MessageEmbed{Title: "Report"})
+	messages := session.Messages()
+	if last := messages[len(messages)-1]; last.ChannelID != "lobby" {
+		t.Errorf("expected the default target to fall back to the remaining guild, got %s", last.ChannelID)
+	}
+}
+
+func TestBotCommandInteraction(t *testing.T) {
+	greet := NewBotFunction("greet", func(req helloRequest) (*Response, error) {
+		if req.Name == "nobody" {
+			return nil, errors.New("lookup failed")
+		}
+		return &Response{Content: "hello " + req.Name}, nil
+	}, nil)
+	admin := NewBotFunction("admin", func(struct{}) (*Response, error) {
+		return &Response{Content: "done"}, nil
+	}, nil, WithPolicy(Policy{GuildOwnerOnly: true}))
+	_, session := newTestBot(t, []BotFunctionI{greet, admin})
+
+	greetData := func(name string) discordgo.ApplicationCommandInteractionData {
+		return discordgo.ApplicationCommandInteractionData{
+			Name: "greet",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: name},
+			},
+		}
+	}
+
+	record := session.Interact(commandInteraction("alice", greetData("bob")))
+	if len(record.Responses) != 1 || record.Responses[0].Data.Content != "hello bob" {
+		t.Errorf("unexpected response %+v", record.Responses)
+	}
+
+	// Internal errors only show an ID, privately.
+	record = session.Interact(commandInteraction("alice", greetData("nobody")))
+	data := record.Responses[0].Data
+	if data.Flags&discordgo.MessageFlagsEphemeral == 0 || !strings.Contains(data.Embeds[0].Description, "error ID") ||
+		strings.Contains(data.Embeds[0].Description, "lookup failed") {
+		t.Errorf("unexpected error response %+v", data.Embeds[0])
+	}
+
+	// The owner is looked up through the session.
+	adminData := discordgo.ApplicationCommandInteractionData{Name: "admin"}
+	record = session.Interact(commandInteraction("alice", adminData))
+	if content := record.Responses[0].Data.Embeds[0].Description; content != permissionDeniedMessage {
+		t.Errorf("expected alice to be denied, got %q", content)
+	}
+	record = session.Interact(commandInteraction("owner", adminData))
+	if content := record.Responses[0].Data.Content; content != "done" {
+		t.Errorf("expected the owner to be allowed, got %q", content)
+	}
+}
+
+func TestBotDeferredNilResponse(t *testing.T) {
+	quiet := NewBotFunction("quiet", func(struct{}) (*Response, error) { return nil, nil }, nil, WithDefer())
+	private := NewBotFunction("private", func(struct{}) (*Response, error) { return nil, nil }, nil, WithDefer(), WithEphemeral())
+	nothing := NewBotFunctionWithContext("nothing", func(inv *Invocation, _ struct{}) (*Response, error) {
+		return &Response{Content: "done"}, inv.Followup(nil)
+	}, nil)
+	_, session := newTestBot(t, []BotFunctionI{quiet, private, nothing})
+
+	for _, name := range []string{"quiet", "private"} {
+		record := session.Interact(commandInteraction("alice", discordgo.ApplicationCommandInteractionData{Name: name}))
+		if len(record.Responses) != 1 || record.Responses[0].Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+			t.Fatalf("%s: expected a deferred response, got %+v", name, record.Responses)
+		}
+		if len(record.Edits) != 1 || len(record.Followups) != 0 || *record.Edits[0].Content != "" {
+			t.Errorf("%s: expected the deferred response to be edited to an empty message, got %+v", name, record)
+		}
+	}
+
+	record := session.Interact(commandInteraction("alice", discordgo.ApplicationCommandInteractionData{Name: "nothing"}))
+	if len(record.Responses) != 1 || record.Responses[0].Data.Content != "done" || len(record.Followups) != 0 {
+		t.Errorf("expected a nil followup to send nothing, got %+v", record)
+	}
+}
+
+func TestBotNotifications(t *testing.T) {
+	bot, session := newTestBot(t, nil, WithTargets(map[string]Target{
+		"alerts": {Channels: []string{"alerts-channel"}, Users: []string{"alice"}},
+	}))
+
+	bot.SendEmbed(&discordgo.MessageEmbed{Title: "Report"})
+	err := bot.SendTo("alerts", &Response{Content: "fire", Files: []*File{{Name: "log.txt", Data: []byte("smoke")}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first message is the online message.
+	messages := session.Messages()[1:]
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	if messages[0].ChannelID != "general" || messages[0].Embeds[0].Title != "Report" {
+		t.Errorf("expected the default target to be the guild's first text channel, got %+v", messages[0])
+	}
+	if messages[1].ChannelID != "alerts-channel" || messages[2].ChannelID != "dm-alice" {
+		t.Errorf("expected the target's channel and a DM, got %s and %s", messages[1].ChannelID, messages[2].ChannelID)
+	}
+	if messages[2].Content != "fire" || len(messages[2].Files) != 1 || messages[2].Files[0].Name != "log.txt" {
+		t.Errorf("unexpected notification %+v", messages[2].MessageSend)
+	}
+}
+
+func TestBotMessageHandlers(t *testing.T) {
+	echo := NewPrefixHandler("echo", "!echo", func(msg *Message) error {
+		if msg.Text == "" {
+			return UserErrorf("Nothing to echo.")
+		}
+		return msg.Reply(msg.Text)
+	})
+	_, session := newTestBot(t, nil, WithMessageHandlers(echo))
+
+	session.SendMessage(&discordgo.Message{ChannelID: "general", Content: "!echo hi", Author: &discordgo.User{ID: "alice"}})
+	session.SendMessage(&discordgo.Message{ChannelID: "general", Content: "!echo", Author: &discordgo.User{ID: "alice"}})
+	// The bot's own messages are ignored.
+	session.SendMessage(&discordgo.Message{ChannelID: "general", Content: "!echo loop", Author: &discordgo.User{ID: "bot"}})
+
+	messages := session.Messages()[1:]
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 replies, got %d", len(messages))
+	}
+	if messages[0].Content != "hi" || messages[0].Reference == nil {
+		t.Errorf("expected a reply echoing the text, got %+v", messages[0].MessageSend)
+	}
+	if messages[1].Embeds[0].Description != "Nothing to echo." {
+		t.Errorf("expected the error as an embed reply, got %+v", messages[1].MessageSend)
+	}
+}
+
+func TestScheduleNotifications(t *testing.T) {
+	bot, session := newTestBot(t, nil)
+	sm := newScheduleManager(bot, nil)
+
+	sm.executeSchedule(NewBotSchedule("quiet", "@daily", func() (*Response, error) { return nil, nil }))
+	sm.executeSchedule(NewBotSchedule("broken", "@daily", func() (*Response, error) { panic("oops") }))
+	sm.executeSchedule(NewBotSchedule("report", "@daily", func() (*Response, error) {
+		return &Response{Content: "monthly report"}, nil
+	}))
+
+	messages := session.Messages()[1:]
+	if len(messages) != 1 || messages[0].Content != "monthly report" {
+		t.Errorf("expected only the report to be sent, got %+v", messages)
+	}
+
+	// Failure notices only show the error ID.
+	sm.executeSchedule(NewBotSchedule("check", "@hourly", func() (*Response, error) {
+		return nil, errors.New("<html>secret page</html>")
+	}, WithFailureNotice()))
+	messages = session.Messages()[2:]
+	if len(messages) != 1 || messages[0].Embeds[0].Title != "Schedule check failed" ||
+		!strings.Contains(messages[0].Embeds[0].Description, "error ID") || strings.Contains(messages[0].Embeds[0].Description, "secret") {
+		t.Errorf("expected a failure notice with only the error ID, got %+v", messages)
+	}
+}
@@ -0,0 +1,253 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Call is a transport-neutral invocation of a command, for running commands through transports
+// other than Discord, such as a terminal. It goes through the same middleware, checks and request
+// decoding as a slash command.
+type Call struct {
+	// Command is the command name followed by any subcommand names, e.g. ["zaps", "export"].
+	Command []string
+	// Options holds the option values by name. Strings are parsed according to the option's type,
+	// so values typed in a terminal work as well as JSON numbers and booleans. Users, channels,
+	// roles and mentionables are given by ID, and attachments by URL.
+	Options map[string]any
+
+	// User is who the command runs as.
+	User *discordgo.User
+	// GuildID is the guild the command runs in, or empty to run it as if in a DM.
+	GuildID string
+	// Roles are the IDs of the user's roles in the guild.
+	Roles []string
+	// Trusted skips the permission checks, for transports only the bot's operator can use.
+	Trusted bool
+
+	// Progress, if set, receives interim status messages from the handler.
+	Progress func(content string)
+	// Followup, if set, receives any additional messages the handler sends.
+	Followup func(resp *Response) error
+}
+
+// Invoke runs a command through the bot's middleware and returns the handler's response.
+// Errors are returned as they are; use the UserError and ValidationError types to tell the ones
+// that are safe to show apart from internal failures.
+func (b *Bot) Invoke(ctx context.Context, call *Call) (*Response, error) {
+	if len(call.Command) == 0 {
+		return nil, UserErrorf("No command given.")
+	}
+	fn := b.findFunction(call.Command[0])
+	if fn == nil {
+		return nil, UserErrorf("Unknown command %s.", call.Command[0])
+	}
+	data, err := callData(fn, call)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	inv := &Invocation{
+		Context:   ctx,
+		User:      call.User,
+		GuildID:   call.GuildID,
+		Data:      data,
+		transport: callTransport{call},
+		trusted:   call.Trusted,
+	}
+	if call.GuildID != "" {
+		inv.Member = &discordgo.Member{GuildID: call.GuildID, User: call.User, Roles: call.Roles}
+	}
+	inv.chain = commandChain(fn, data)
+	inv.Command = commandName(inv.chain)
+
+	resp, err := b.commandHandler(inv.chain)(inv)
+	if err != nil {
+		return nil, err
+	}
+	return allPages(resp)
+}
+
+// callData builds the interaction data Discord would send for the call, checking the options
+// against the command's definition as Discord's client would.
+func callData(fn BotFunctionI, call *Call) (*discordgo.ApplicationCommandInteractionData, error) {
+	cmd, err := fn.GetCommand()
+	if err != nil {
+		return nil, err
+	}
+	data := &discordgo.ApplicationCommandInteractionData{
+		Name:        cmd.Name,
+		CommandType: discordgo.ChatApplicationCommand,
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Users:       make(map[string]*discordgo.User),
+			Members:     make(map[string]*discordgo.Member),
+			Roles:       make(map[string]*discordgo.Role),
+			Channels:    make(map[string]*discordgo.Channel),
+			Attachments: make(map[string]*discordgo.MessageAttachment),
+		},
+	}
+
+	// Walk down to the subcommand, nesting an option for each level.
+	options := &data.Options
+	definitions := cmd.Options
+	for depth, name := range call.Command[1:] {
+		i := slices.IndexFunc(definitions, func(def *discordgo.ApplicationCommandOption) bool {
+			return def.Name == name && isSubcommand(def.Type)
+		})
+		if i < 0 {
+			return nil, UserErrorf("Unknown subcommand %s.", strings.Join(call.Command[:depth+2], " "))
+		}
+		opt := &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: definitions[i].Type}
+		*options = append(*options, opt)
+		options = &opt.Options
+		definitions = definitions[i].Options
+	}
+	if len(definitions) > 0 && isSubcommand(definitions[0].Type) {
+		var names []string
+		for _, def := range definitions {
+			names = append(names, def.Name)
+		}
+		return nil, UserErrorf("%s needs a subcommand: %s.", strings.Join(call.Command, " "), strings.Join(names, ", "))
+	}
+
+	var verr ValidationError
+	for _, name := range slices.Sorted(maps.Keys(call.Options)) {
+		i := slices.IndexFunc(definitions, func(def *discordgo.ApplicationCommandOption) bool { return def.Name == name })
+		if i < 0 {
+			verr.Fields = append(verr.Fields, FieldError{Field: name, Message: "unknown option"})
+			continue
+		}
+		def := definitions[i]
+		value, err := callValue(def, call.Options[name], data.Resolved)
+		if err != nil {
+			verr.Fields = append(verr.Fields, FieldError{Field: name, Message: err.Error()})
+			continue
+		}
+		*options = append(*options, &discordgo.ApplicationCommandInteractionDataOption{Name: name, Type: def.Type, Value: value})
+	}
+	for _, def := range definitions {
+		if _, ok := call.Options[def.Name]; def.Required && !ok {
+			verr.Fields = append(verr.Fields, FieldError{Field: def.Name, Message: "is required"})
+		}
+	}
+	if len(verr.Fields) > 0 {
+		return nil, &verr
+	}
+	return data, nil
+}
+
+// isSubcommand reports whether an option type is a subcommand or subcommand group.
+func isSubcommand(t discordgo.ApplicationCommandOptionType) bool {
+	return t == discordgo.ApplicationCommandOptionSubCommand || t == discordgo.ApplicationCommandOptionSubCommandGroup
+}
+
+// callValue converts an option value from a call into the value Discord would send, enforcing the
+// option's choices and limits. IDs of users, channels and roles are added to resolved with just
+// the ID filled in, as there is no Discord to look them up in.
+func callValue(def *discordgo.ApplicationCommandOption, value any, resolved *discordgo.ApplicationCommandInteractionDataResolved) (any, error) {
+	s, isString := value.(string)
+	var v any
+	switch def.Type {
+	case discordgo.ApplicationCommandOptionString:
+		if !isString {
+			return nil, errors.New("must be text")
+		}
+		if def.MinLength != nil && utf8.RuneCountInString(s) < *def.MinLength {
+			return nil, fmt.Errorf("must be at least %d characters", *def.MinLength)
+		}
+		if def.MaxLength > 0 && utf8.RuneCountInString(s) > def.MaxLength {
+			return nil, fmt.Errorf("must be at most %d characters", def.MaxLength)
+		}
+		v = s
+	case discordgo.ApplicationCommandOptionInteger, discordgo.ApplicationCommandOptionNumber:
+		n, ok := value.(float64)
+		if isString {
+			var err error
+			n, err = strconv.ParseFloat(s, 64)
+			ok = err == nil
+		}
+		if !ok || (def.Type == discordgo.ApplicationCommandOptionInteger && n != math.Trunc(n)) {
+			return nil, fmt.Errorf("must be a %s", strings.ToLower(def.Type.String()))
+		}
+		if def.MinValue != nil && n < *def.MinValue {
+			return nil, fmt.Errorf("must be at least %v", *def.MinValue)
+		}
+		if def.MaxValue != 0 && n > def.MaxValue {
+			return nil, fmt.Errorf("must be at most %v", def.MaxValue)
+		}
+		v = n
+	case discordgo.ApplicationCommandOptionBoolean:
+		b, ok := value.(bool)
+		if isString {
+			var err error
+			b, err = strconv.ParseBool(s)
+			ok = err == nil
+		}
+		if !ok {
+			return nil, errors.New("must be true or false")
+		}
+		v = b
+	default:
+		if !isString || s == "" {
+			return nil, errors.New("must be an ID")
+		}
+		switch def.Type {
+		case discordgo.ApplicationCommandOptionUser, discordgo.ApplicationCommandOptionMentionable:
+			resolved.Users[s] = &discordgo.User{ID: s}
+		case discordgo.ApplicationCommandOptionChannel:
+			resolved.Channels[s] = &discordgo.Channel{ID: s}
+		case discordgo.ApplicationCommandOptionRole:
+			resolved.Roles[s] = &discordgo.Role{ID: s}
+		case discordgo.ApplicationCommandOptionAttachment:
+			resolved.Attachments[s] = &discordgo.MessageAttachment{ID: s, URL: s}
+		}
+		v = s
+	}
+
+	if len(def.Choices) > 0 && !slices.ContainsFunc(def.Choices, func(c *discordgo.ApplicationCommandOptionChoice) bool {
+		return fmt.Sprint(c.Value) == fmt.Sprint(v)
+	}) {
+		var names []string
+		for _, c := range def.Choices {
+			names = append(names, fmt.Sprint(c.Value))
+		}
+		return nil, fmt.Errorf("must be one of %s", strings.Join(names, ", "))
+	}
+	return v, nil
+}
+
+// callTransport answers a Call through its callbacks. Calls aren't visible to anyone but the
+// caller and have no response deadline, so visibility and deferral don't apply.
+type callTransport struct {
+	call *Call
+}
+
+func (t callTransport) setEphemeral(bool)          {}
+func (t callTransport) isEphemeral(*Response) bool { return false }
+func (t callTransport) deferResponse() error       { return nil }
+func (t callTransport) autoDefer()                 {}
+
+func (t callTransport) progress(content string) error {
+	if t.call.Progress != nil {
+		t.call.Progress(content)
+	}
+	return nil
+}
+
+func (t callTransport) followup(resp *Response) error {
+	if t.call.Followup == nil {
+		return nil
+	}
+	return t.call.Followup(resp)
+}
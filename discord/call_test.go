@@ -0,0 +1,78 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type callRequest struct {
+	Name  string
+	Count int       `discord:"optional,min:1,max:5"`
+	Loud  bool      `discord:"optional"`
+	Day   time.Time `discord:"optional,layout:2006/01/02"`
+	Owner *discordgo.User
+	Size  string `discord:"optional,choices:s|Small;l|Large"`
+}
+
+func TestInvoke(t *testing.T) {
+	var got callRequest
+	var progress []string
+	fn := NewBotFunctionWithContext("greet", func(inv *Invocation, req callRequest) (*Response, error) {
+		got = req
+		inv.Progress("working")
+		return &Response{Content: "hi " + req.Name}, nil
+	}, nil, WithPolicy(Policy{AllowedUsers: []string{"alice"}}))
+	group := NewBotFunctionGroup("admin", []BotFunctionI{
+		NewBotFunction("ping", func(struct{}) (*Response, error) { return &Response{Content: "pong"}, nil }, nil),
+	})
+
+	b, err := NewLocalBot([]BotFunctionI{fn, group})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := &Call{
+		Command:  []string{"greet"},
+		Options:  map[string]any{"name": "bob", "count": "3", "loud": "true", "day": "2025/03/14", "owner": "42", "size": "l"},
+		User:     &discordgo.User{ID: "alice"},
+		Progress: func(content string) { progress = append(progress, content) },
+	}
+	resp, err := b.Invoke(context.Background(), call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Content != "hi bob" || got.Count != 3 || !got.Loud || got.Day.Day() != 14 || got.Owner.ID != "42" || got.Size != "l" {
+		t.Errorf("unexpected result %q for %+v", resp.Content, got)
+	}
+	if len(progress) != 1 {
+		t.Errorf("expected progress to reach the caller, got %v", progress)
+	}
+
+	call.User = &discordgo.User{ID: "mallory"}
+	if _, err := b.Invoke(context.Background(), call); err == nil {
+		t.Error("expected the policy to apply to calls")
+	}
+	call.Trusted = true
+	if _, err := b.Invoke(context.Background(), call); err != nil {
+		t.Errorf("expected trusted calls to skip the policy, got %v", err)
+	}
+
+	call.Options = map[string]any{"count": float64(9), "size": "m", "extra": "x"}
+	_, err = b.Invoke(context.Background(), call)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Fields) != 5 {
+		t.Errorf("expected count, extra, size, name and owner to be invalid, got %v", err)
+	}
+
+	resp, err = b.Invoke(context.Background(), &Call{Command: []string{"admin", "ping"}})
+	if err != nil || resp.Content != "pong" {
+		t.Errorf("unexpected subcommand result %v, %v", resp, err)
+	}
+	if _, err := b.Invoke(context.Background(), &Call{Command: []string{"admin"}}); err == nil {
+		t.Error("expected a missing subcommand to be rejected")
+	}
+}
@@ -0,0 +1,180 @@
+package discord
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxCustomIDLength is the longest custom ID Discord accepts on a message component.
+const maxCustomIDLength = 100
+
+// customIDSeparator separates the component name from its encoded state in a custom ID.
+const customIDSeparator = "?"
+
+// BotComponentI is the common interface for handlers of message components such as buttons and select menus.
+// Components are routed by name: the custom ID of a component is its name optionally followed by
+// encoded state, as produced by CustomID.
+type BotComponentI interface {
+	GetName() string
+	// HandleComponent decodes the state from the custom ID, calls the handler and returns
+	// the response to send to Discord.
+	HandleComponent(data *discordgo.MessageComponentInteractionData) (*Response, error)
+	// UpdatesMessage reports whether the response should replace the message the component
+	// is attached to rather than be sent as a new message.
+	UpdatesMessage() bool
+	// GetConfig returns the optional settings the component was created with, such as its access policy.
+	GetConfig() FunctionConfig
+}
+
+// GenericBotComponent is a generic implementation of BotComponentI.
+type GenericBotComponent[T Request] struct {
+	// Name is the prefix of the custom IDs this component handles.
+	Name string
+	// Handler is called with the state decoded from the custom ID and, for select menus,
+	// the values the user picked.
+	Handler func(state T, values []string) (*Response, error)
+	// Reply, when true, answers with a new message instead of updating the message
+	// the component is attached to.
+	Reply bool
+	// Config holds optional settings for the component. Its policy, cooldowns and in-flight limit
+	// are checked before the handler runs.
+	Config FunctionConfig
+}
+
+// GetName returns the component's name.
+func (bc *GenericBotComponent[T]) GetName() string {
+	return bc.Name
+}
+
+// UpdatesMessage reports whether the component edits the message it belongs to.
+func (bc *GenericBotComponent[T]) UpdatesMessage() bool {
+	return !bc.Reply
+}
+
+// GetConfig returns the component's optional settings.
+func (bc *GenericBotComponent[T]) GetConfig() FunctionConfig {
+	return bc.Config
+}
+
+// CustomID builds a custom ID that routes back to this component carrying the given state.
+func (bc *GenericBotComponent[T]) CustomID(state T) (string, error) {
+	return CustomID(bc.Name, state)
+}
+
+// HandleComponent decodes the state encoded in the custom ID into a value of type T and calls the handler.
+func (bc *GenericBotComponent[T]) HandleComponent(data *discordgo.MessageComponentInteractionData) (*Response, error) {
+	var state T
+
+	_, values, err := parseCustomID(data.CustomID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = decodeRequest(values, &state)
+	if err != nil {
+		return nil, err
+	}
+
+	return bc.Handler(state, data.Values)
+}
+
+// NewBotComponent creates a component handler for buttons and select menus whose custom IDs
+// start with name. The state type T is encoded into the custom ID by CustomID, using the
+// lower-cased field names as keys in the same way request structs map to command options.
+// By default the handler's response replaces the message the component is attached to.
+// Anyone who can see a message can use its components, so components that fetch or change data
+// should be given the policy of the command that sends them with WithPolicy.
+func NewBotComponent[T Request](name string, handler func(state T, values []string) (*Response, error), opts ...FunctionOption) *GenericBotComponent[T] {
+	return &GenericBotComponent[T]{
+		Name:    name,
+		Handler: handler,
+		Config:  newFunctionConfig(opts),
+	}
+}
+
+// runComponent calls the component's handler if its policy allows the invocation and it isn't
+// cooling down or already running too many times, as commands are checked.
+func (b *Bot) runComponent(inv *Invocation, component BotComponentI, data *discordgo.MessageComponentInteractionData) (*Response, error) {
+	if !b.allowed(inv, component) {
+		return nil, &UserError{Message: permissionDeniedMessage, Err: ErrPermissionDenied}
+	}
+	release, wait := b.limits.acquire(inv, componentLimits(component))
+	if release == nil {
+		return nil, &UserError{Message: wait, Err: ErrRateLimited}
+	}
+	defer release()
+	return component.HandleComponent(data)
+}
+
+// CustomID encodes state into a custom ID for the component registered under name.
+// State must be a struct (or pointer to one) with primitive fields, or nil for no state.
+// Zero-valued fields are omitted to keep the ID within Discord's 100 character limit.
+func CustomID(name string, state Request) (string, error) {
+	if strings.Contains(name, customIDSeparator) {
+		return "", fmt.Errorf("component name %q must not contain %q", name, customIDSeparator)
+	}
+
+	values := url.Values{}
+	if state != nil {
+		v := reflect.ValueOf(state)
+		if v.Kind() == reflect.Ptr {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return "", fmt.Errorf("component state is not a struct")
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			fieldVal := v.Field(i)
+			if !field.IsExported() || isZero(fieldVal) {
+				continue
+			}
+			if isTextType(field.Type) {
+				text, err := formatText(fieldVal, parseDiscordTag(field.Tag.Get("discord")))
+				if err != nil {
+					return "", fmt.Errorf("failed to encode component state field %s: %w", field.Name, err)
+				}
+				values.Set(strings.ToLower(field.Name), text)
+				continue
+			}
+			switch fieldVal.Kind() {
+			case reflect.String, reflect.Bool,
+				reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Float32, reflect.Float64:
+				values.Set(strings.ToLower(field.Name), fmt.Sprint(fieldVal.Interface()))
+			default:
+				return "", fmt.Errorf("unsupported type for component state field %s: %s", field.Name, fieldVal.Kind())
+			}
+		}
+	}
+
+	id := name
+	if len(values) > 0 {
+		id += customIDSeparator + values.Encode()
+	}
+	if len(id) > maxCustomIDLength {
+		return "", fmt.Errorf("custom ID for component %s is %d characters, the limit is %d", name, len(id), maxCustomIDLength)
+	}
+	return id, nil
+}
+
+// parseCustomID splits a custom ID into the component name and its decoded state values.
+func parseCustomID(customID string) (string, map[string]interface{}, error) {
+	name, encoded, _ := strings.Cut(customID, customIDSeparator)
+
+	query, err := url.ParseQuery(encoded)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse state for component %s: %w", name, err)
+	}
+
+	values := make(map[string]interface{}, len(query))
+	for key := range query {
+		values[key] = query.Get(key)
+	}
+	return name, values, nil
+}
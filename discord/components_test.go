@@ -0,0 +1,81 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type pageState struct {
+	Query string `discord:"optional,description:Search text"`
+	Page  int    `discord:"optional,default:1"`
+}
+
+func TestCustomIDRoundTrip(t *testing.T) {
+	var got pageState
+	component := NewBotComponent("page", func(state pageState, values []string) (*Response, error) {
+		got = state
+		return &Response{}, nil
+	})
+
+	id, err := component.CustomID(pageState{Query: "a&b", Page: 3})
+	if err != nil {
+		t.Fatalf("CustomID: %v", err)
+	}
+	if id != "page?page=3&query=a%26b" {
+		t.Fatalf("unexpected custom ID %q", id)
+	}
+
+	_, err = component.HandleComponent(&discordgo.MessageComponentInteractionData{CustomID: id})
+	if err != nil {
+		t.Fatalf("HandleComponent: %v", err)
+	}
+	if got.Query != "a&b" || got.Page != 3 {
+		t.Fatalf("unexpected state %+v", got)
+	}
+
+	// A bare name decodes to the zero state with defaults applied.
+	_, err = component.HandleComponent(&discordgo.MessageComponentInteractionData{CustomID: "page"})
+	if err != nil {
+		t.Fatalf("HandleComponent: %v", err)
+	}
+	if got.Query != "" || got.Page != 1 {
+		t.Fatalf("unexpected default state %+v", got)
+	}
+}
+
+func TestCustomIDTooLong(t *testing.T) {
+	long := make([]byte, maxCustomIDLength)
+	for i := range long {
+		long[i] = 'x'
+	}
+	_, err := CustomID("page", pageState{Query: string(long)})
+	if err == nil {
+		t.Fatal("expected an error for a custom ID over the length limit")
+	}
+}
+
+func TestComponentPolicy(t *testing.T) {
+	refresh := NewBotComponent("refresh", func(state struct{}, values []string) (*Response, error) {
+		return &Response{Content: "fresh"}, nil
+	}, WithPolicy(Policy{AllowedUsers: []string{"alice"}}))
+	_, session := newTestBot(t, nil, WithComponents(refresh))
+
+	click := func(userID string) *discordgo.InteractionResponseData {
+		record := session.Interact(&discordgo.Interaction{
+			Type:      discordgo.InteractionMessageComponent,
+			GuildID:   "g1",
+			ChannelID: "general",
+			Member:    &discordgo.Member{User: &discordgo.User{ID: userID}},
+			Data:      discordgo.MessageComponentInteractionData{CustomID: "refresh", ComponentType: discordgo.ButtonComponent},
+		})
+		return record.Responses[0].Data
+	}
+
+	if data := click("bob"); len(data.Embeds) != 1 || data.Embeds[0].Description != permissionDeniedMessage {
+		t.Errorf("expected bob to be denied, got %+v", data)
+	}
+	if data := click("alice"); data.Content != "fresh" {
+		t.Errorf("expected alice to be allowed, got %+v", data)
+	}
+}
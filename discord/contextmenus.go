@@ -0,0 +1,134 @@
+package discord
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// BotContextMenuI is a command shown in the "Apps" menu when right-clicking a user or a message.
+// Context menu commands run through the same middleware, permission checks, limits and response
+// settings as slash commands, but take the targeted user or message instead of options.
+type BotContextMenuI interface {
+	BotFunctionI
+	// GetType returns discordgo.UserApplicationCommand or discordgo.MessageApplicationCommand.
+	GetType() discordgo.ApplicationCommandType
+}
+
+// GenericContextMenu is an implementation of BotContextMenuI.
+type GenericContextMenu struct {
+	// Name is shown in the menu. Unlike slash command names it may contain spaces and capitals,
+	// e.g. "Save as note".
+	Name string
+	// Type is discordgo.UserApplicationCommand or discordgo.MessageApplicationCommand.
+	Type discordgo.ApplicationCommandType
+	// Handler is called with the interaction data, whose TargetID and resolved data identify the target.
+	Handler func(inv *Invocation, data *discordgo.ApplicationCommandInteractionData) (*Response, error)
+	// Config holds optional settings controlling how the bot runs the command. Descriptions and
+	// examples are unused, as Discord shows context menu commands by name only.
+	Config FunctionConfig
+}
+
+// GetName returns the command's name.
+func (cm *GenericContextMenu) GetName() string {
+	return cm.Name
+}
+
+// GetType returns whether the command is shown on users or on messages.
+func (cm *GenericContextMenu) GetType() discordgo.ApplicationCommandType {
+	return cm.Type
+}
+
+// GetRequestPrototype returns nil, as context menu commands take no options.
+func (cm *GenericContextMenu) GetRequestPrototype() Request {
+	return nil
+}
+
+// GetConfig returns the command's optional settings.
+func (cm *GenericContextMenu) GetConfig() FunctionConfig {
+	return cm.Config
+}
+
+// GetCommand builds the context menu command. Discord rejects descriptions and options on them.
+func (cm *GenericContextMenu) GetCommand() (*discordgo.ApplicationCommand, error) {
+	if cm.Type != discordgo.UserApplicationCommand && cm.Type != discordgo.MessageApplicationCommand {
+		return nil, fmt.Errorf("context menu command %s must be a user or message command", cm.Name)
+	}
+	cmd := &discordgo.ApplicationCommand{
+		Type: cm.Type,
+		Name: cm.Name,
+	}
+	applyConfig(cmd, cm.Config)
+	return cmd, nil
+}
+
+// HandleInteraction calls the handler.
+func (cm *GenericContextMenu) HandleInteraction(inv *Invocation, data *discordgo.ApplicationCommandInteractionData) (*Response, error) {
+	return cm.Handler(inv, data)
+}
+
+// HandleAutocomplete returns an error, as context menu commands have no options to complete.
+func (cm *GenericContextMenu) HandleAutocomplete(data *discordgo.ApplicationCommandInteractionData) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	return nil, fmt.Errorf("context menu command %s does not support autocomplete", cm.Name)
+}
+
+// NewUserCommand creates a command shown when right-clicking a user. The handler is given the
+// targeted user and, when the command was run in a guild the user is in, their member with User set.
+func NewUserCommand(name string, handler func(inv *Invocation, user *discordgo.User, member *discordgo.Member) (*Response, error), opts ...FunctionOption) BotContextMenuI {
+	return &GenericContextMenu{
+		Name: name,
+		Type: discordgo.UserApplicationCommand,
+		Handler: func(inv *Invocation, data *discordgo.ApplicationCommandInteractionData) (*Response, error) {
+			if data.Resolved == nil || data.Resolved.Users[data.TargetID] == nil {
+				return nil, fmt.Errorf("targeted user %s is missing from the interaction data", data.TargetID)
+			}
+			user := data.Resolved.Users[data.TargetID]
+			var member *discordgo.Member
+			if m := data.Resolved.Members[data.TargetID]; m != nil {
+				// Discord leaves the user out of resolved members.
+				withUser := *m
+				withUser.User = user
+				member = &withUser
+			}
+			return handler(inv, user, member)
+		},
+		Config: newFunctionConfig(opts),
+	}
+}
+
+// NewMessageCommand creates a command shown when right-clicking a message. The handler is given
+// the targeted message.
+func NewMessageCommand(name string, handler func(inv *Invocation, msg *discordgo.Message) (*Response, error), opts ...FunctionOption) BotContextMenuI {
+	return &GenericContextMenu{
+		Name: name,
+		Type: discordgo.MessageApplicationCommand,
+		Handler: func(inv *Invocation, data *discordgo.ApplicationCommandInteractionData) (*Response, error) {
+			if data.Resolved == nil || data.Resolved.Messages[data.TargetID] == nil {
+				return nil, fmt.Errorf("targeted message %s is missing from the interaction data", data.TargetID)
+			}
+			return handler(inv, data.Resolved.Messages[data.TargetID])
+		},
+		Config: newFunctionConfig(opts),
+	}
+}
+
+// WithContextMenus registers context menu commands, shown when right-clicking users or messages.
+func WithContextMenus(menus ...BotContextMenuI) BotOption {
+	return func(b *Bot) {
+		b.contextMenus = append(b.contextMenus, menus...)
+	}
+}
+
+// findCommand returns the function that handles an application command. Context menu commands
+// are matched by type as well as name, since they may share a name with a slash command.
+func (b *Bot) findCommand(data *discordgo.ApplicationCommandInteractionData) BotFunctionI {
+	if data.CommandType == 0 || data.CommandType == discordgo.ChatApplicationCommand {
+		return b.findFunction(data.Name)
+	}
+	for _, m := range b.contextMenus {
+		if m.GetType() == data.CommandType && m.GetName() == data.Name {
+			return m
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestContextMenuCommands(t *testing.T) {
+	var notes []string
+	saveNote := NewMessageCommand("Save as note", func(inv *Invocation, msg *discordgo.Message) (*Response, error) {
+		notes = append(notes, msg.Content)
+		return &Response{Content: "Saved."}, nil
+	}, WithEphemeral())
+	roles := NewUserCommand("roles", func(inv *Invocation, user *discordgo.User, member *discordgo.Member) (*Response, error) {
+		return &Response{Content: user.Username + ": " + strings.Join(member.Roles, ", ")}, nil
+	}, WithPolicy(Policy{AllowedUsers: []string{"alice"}}))
+	// A slash command with the same name as a context menu command is a separate command.
+	slash := NewBotFunction("roles", func(struct{}) (*Response, error) {
+		return &Response{Content: "slash"}, nil
+	}, nil)
+	_, session := newTestBot(t, []BotFunctionI{slash}, WithContextMenus(saveNote, roles))
+
+	types := make(map[string]discordgo.ApplicationCommandType)
+	for _, cmd := range session.Commands("g1") {
+		types[cmd.Name] = commandType(cmd)
+	}
+	if types["Save as note"] != discordgo.MessageApplicationCommand || len(session.Commands("g1")) != 4 {
+		t.Errorf("expected both context menu commands to be registered alongside the slash commands, got %v", types)
+	}
+
+	record := session.Interact(commandInteraction("alice", discordgo.ApplicationCommandInteractionData{
+		Name:        "Save as note",
+		CommandType: discordgo.MessageApplicationCommand,
+		TargetID:    "m1",
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Messages: map[string]*discordgo.Message{"m1": {ID: "m1", Content: "buy milk"}},
+		},
+	}))
+	data := record.Responses[0].Data
+	if data.Content != "Saved." || data.Flags&discordgo.MessageFlagsEphemeral == 0 || len(notes) != 1 || notes[0] != "buy milk" {
+		t.Errorf("unexpected response %+v with notes %v", data, notes)
+	}
+
+	showData := discordgo.ApplicationCommandInteractionData{
+		Name:        "roles",
+		CommandType: discordgo.UserApplicationCommand,
+		TargetID:    "u1",
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Users:   map[string]*discordgo.User{"u1": {ID: "u1", Username: "bob"}},
+			Members: map[string]*discordgo.Member{"u1": {Roles: []string{"admin"}}},
+		},
+	}
+	record = session.Interact(commandInteraction("alice", showData))
+	if content := record.Responses[0].Data.Content; content != "bob: admin" {
+		t.Errorf("unexpected response %q", content)
+	}
+	record = session.Interact(commandInteraction("carol", showData))
+	if content := record.Responses[0].Data.Embeds[0].Description; content != permissionDeniedMessage {
+		t.Errorf("expected the policy to apply, got %q", content)
+	}
+	record = session.Interact(commandInteraction("carol", discordgo.ApplicationCommandInteractionData{Name: "roles"}))
+	if content := record.Responses[0].Data.Content; content != "slash" {
+		t.Errorf("expected the slash command, got %q", content)
+	}
+}
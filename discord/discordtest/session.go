@@ -0,0 +1,480 @@
+// Package discordtest provides an in-memory fake of the Discord session used by the discord
+// package, so bots can be tested without connecting to Discord. The fake records everything the
+// bot sends, and tests drive the bot by adding guilds and injecting messages and interactions.
+//
+// Events are delivered to handlers synchronously, on the goroutine that injects them, so a test
+// can check what was sent as soon as the injecting call returns.
+package discordtest
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Message is a message the bot sent to a channel.
+type Message struct {
+	// ID is the ID the fake gave the message.
+	ID        string
+	ChannelID string
+	*discordgo.MessageSend
+}
+
+// Reaction is a reaction the bot added to a message.
+type Reaction struct {
+	ChannelID string
+	MessageID string
+	Emoji     string
+}
+
+// Interaction records what the bot sent in answer to an interaction, in the order it was sent.
+type Interaction struct {
+	// Responses are the initial responses. Discord only accepts one.
+	Responses []*discordgo.InteractionResponse
+	// Edits are the edits of the initial response.
+	Edits []*discordgo.WebhookEdit
+	// Followups are the followup messages.
+	Followups []*discordgo.WebhookParams
+	// Deleted reports whether the initial response was deleted.
+	Deleted bool
+}
+
+// Session is a fake Discord session. The zero value isn't usable; create one with NewSession.
+type Session struct {
+	mu       sync.Mutex
+	userID   string
+	open     bool
+	nextID   int
+	handlers []interface{}
+	guilds   []*discordgo.Guild
+	channels map[string][]*discordgo.Channel
+	// commands holds the registered application commands by guild ID, with "" for global commands.
+	commands     map[string][]*discordgo.ApplicationCommand
+	messages     []*Message
+	reactions    []Reaction
+	interactions map[string]*Interaction
+}
+
+// NewSession creates a fake session for a bot whose user has the given ID.
+func NewSession(botUserID string) *Session {
+	return &Session{
+		userID:       botUserID,
+		channels:     make(map[string][]*discordgo.Channel),
+		commands:     make(map[string][]*discordgo.ApplicationCommand),
+		interactions: make(map[string]*Interaction),
+	}
+}
+
+// discordEpoch is the start of time for Discord snowflakes, in Unix milliseconds.
+const discordEpoch = 1420070400000
+
+// newID returns a new unique snowflake for the current time, so that interactions with it are
+// treated as just received. The caller must hold mu.
+func (s *Session) newID() string {
+	s.nextID++
+	ms := time.Now().UnixMilli() - discordEpoch
+	return strconv.FormatInt(ms<<22|int64(s.nextID&0xFFF), 10)
+}
+
+// Open marks the session open and reports each guild added so far, as Discord does on connect.
+func (s *Session) Open() error {
+	s.mu.Lock()
+	if s.open {
+		s.mu.Unlock()
+		return fmt.Errorf("session is already open")
+	}
+	s.open = true
+	guilds := slices.Clone(s.guilds)
+	s.mu.Unlock()
+
+	for _, g := range guilds {
+		s.dispatch(&discordgo.GuildCreate{Guild: g})
+	}
+	return nil
+}
+
+// Close marks the session closed.
+func (s *Session) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.open = false
+	return nil
+}
+
+// AddHandler adds an event handler with one of discordgo's handler signatures. The fake delivers
+// GuildCreate, GuildDelete, ChannelCreate, ThreadCreate, MessageCreate and InteractionCreate events, passing a nil
+// *discordgo.Session. The returned function removes the handler.
+func (s *Session) AddHandler(handler interface{}) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, handler)
+	index := len(s.handlers) - 1
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.handlers[index] = nil
+	}
+}
+
+// dispatch calls every handler for the event's type.
+func (s *Session) dispatch(event interface{}) {
+	s.mu.Lock()
+	handlers := slices.Clone(s.handlers)
+	s.mu.Unlock()
+
+	for _, h := range handlers {
+		switch h := h.(type) {
+		case func(*discordgo.Session, *discordgo.GuildCreate):
+			if e, ok := event.(*discordgo.GuildCreate); ok {
+				h(nil, e)
+			}
+		case func(*discordgo.Session, *discordgo.GuildDelete):
+			if e, ok := event.(*discordgo.GuildDelete); ok {
+				h(nil, e)
+			}
+		case func(*discordgo.Session, *discordgo.ChannelCreate):
+			if e, ok := event.(*discordgo.ChannelCreate); ok {
+				h(nil, e)
+			}
+		case func(*discordgo.Session, *discordgo.ThreadCreate):
+			if e, ok := event.(*discordgo.ThreadCreate); ok {
+				h(nil, e)
+			}
+		case func(*discordgo.Session, *discordgo.MessageCreate):
+			if e, ok := event.(*discordgo.MessageCreate); ok {
+				h(nil, e)
+			}
+		case func(*discordgo.Session, *discordgo.InteractionCreate):
+			if e, ok := event.(*discordgo.InteractionCreate); ok {
+				h(nil, e)
+			}
+		}
+	}
+}
+
+// AddGuild adds a guild and its channels to the bot's state. The channels are also set on the guild,
+// as Discord includes them when reporting a guild. Once the session is open, the bot is told about
+// it as if it had just been invited.
+func (s *Session) AddGuild(guild *discordgo.Guild, channels ...*discordgo.Channel) {
+	s.mu.Lock()
+	s.guilds = append(s.guilds, guild)
+	for _, c := range channels {
+		c.GuildID = guild.ID
+	}
+	guild.Channels = append(guild.Channels, channels...)
+	s.channels[guild.ID] = append(s.channels[guild.ID], channels...)
+	open := s.open
+	s.mu.Unlock()
+
+	if open {
+		s.dispatch(&discordgo.GuildCreate{Guild: guild})
+	}
+}
+
+// AddChannel adds a channel or thread to a guild and tells the bot it was created.
+func (s *Session) AddChannel(guildID string, channel *discordgo.Channel) {
+	s.mu.Lock()
+	channel.GuildID = guildID
+	s.channels[guildID] = append(s.channels[guildID], channel)
+	s.mu.Unlock()
+
+	if channel.IsThread() {
+		s.dispatch(&discordgo.ThreadCreate{Channel: channel, NewlyCreated: true})
+		return
+	}
+	s.dispatch(&discordgo.ChannelCreate{Channel: channel})
+}
+
+// RemoveGuild removes a guild from the bot's state and tells the bot it was removed from it.
+func (s *Session) RemoveGuild(guildID string) {
+	s.mu.Lock()
+	s.guilds = slices.DeleteFunc(s.guilds, func(g *discordgo.Guild) bool { return g.ID == guildID })
+	delete(s.channels, guildID)
+	s.mu.Unlock()
+
+	s.dispatch(&discordgo.GuildDelete{Guild: &discordgo.Guild{ID: guildID}})
+}
+
+// SendMessage delivers a message to the bot as if a user had sent it. It is given an ID if it has none.
+func (s *Session) SendMessage(m *discordgo.Message) {
+	s.mu.Lock()
+	if m.ID == "" {
+		m.ID = s.newID()
+	}
+	s.mu.Unlock()
+
+	s.dispatch(&discordgo.MessageCreate{Message: m})
+}
+
+// Interact delivers an interaction to the bot, such as a slash command or a button click, and
+// returns the record of what the bot sent in answer once the handlers return. The interaction is
+// given an ID and token if it has none. Responses sent after the handlers return, such as edits of
+// a deferred response, are picked up by calling Interaction with the interaction's ID.
+func (s *Session) Interact(i *discordgo.Interaction) *Interaction {
+	s.mu.Lock()
+	if i.ID == "" {
+		i.ID = s.newID()
+	}
+	if i.Token == "" {
+		i.Token = "token-" + i.ID
+	}
+	s.interactions[i.ID] = &Interaction{}
+	s.mu.Unlock()
+
+	s.dispatch(&discordgo.InteractionCreate{Interaction: i})
+	return s.Interaction(i.ID)
+}
+
+// Interaction returns a copy of the record of what the bot sent in answer to the interaction.
+func (s *Session) Interaction(id string) *Interaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.interactions[id]
+	if !ok {
+		return &Interaction{}
+	}
+	return &Interaction{
+		Responses: slices.Clone(record.Responses),
+		Edits:     slices.Clone(record.Edits),
+		Followups: slices.Clone(record.Followups),
+		Deleted:   record.Deleted,
+	}
+}
+
+// Messages returns the messages the bot has sent to channels, in the order they were sent.
+func (s *Session) Messages() []*Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.messages)
+}
+
+// Reactions returns the reactions the bot has added, in the order they were added.
+func (s *Session) Reactions() []Reaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.reactions)
+}
+
+// Commands returns the application commands registered in the guild, or globally for "".
+func (s *Session) Commands(guildID string) []*discordgo.ApplicationCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands[guildID])
+}
+
+// BotUserID returns the bot user's ID.
+func (s *Session) BotUserID() string {
+	return s.userID
+}
+
+// Guilds returns the guilds the bot is in.
+func (s *Session) Guilds() []*discordgo.Guild {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.guilds)
+}
+
+// Guild returns a guild the bot is in.
+func (s *Session) Guild(guildID string, _ ...discordgo.RequestOption) (*discordgo.Guild, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, g := range s.guilds {
+		if g.ID == guildID {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown guild %s", guildID)
+}
+
+// GuildChannels returns the channels of a guild the bot is in.
+func (s *Session) GuildChannels(guildID string, _ ...discordgo.RequestOption) ([]*discordgo.Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	channels, ok := s.channels[guildID]
+	if !ok {
+		return nil, fmt.Errorf("unknown guild %s", guildID)
+	}
+	return slices.Clone(channels), nil
+}
+
+// UserChannelCreate returns the DM channel with a user, whose ID is "dm-" followed by the user's ID.
+func (s *Session) UserChannelCreate(recipientID string, _ ...discordgo.RequestOption) (*discordgo.Channel, error) {
+	return &discordgo.Channel{
+		ID:         "dm-" + recipientID,
+		Type:       discordgo.ChannelTypeDM,
+		Recipients: []*discordgo.User{{ID: recipientID}},
+	}, nil
+}
+
+// ApplicationCommands returns the commands registered in the guild, or globally for "".
+func (s *Session) ApplicationCommands(_, guildID string, _ ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	return s.Commands(guildID), nil
+}
+
+// ApplicationCommandCreate registers a command, replacing any of the same type and name as Discord does.
+func (s *Session) ApplicationCommandCreate(appID, guildID string, cmd *discordgo.ApplicationCommand, _ ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	created := *cmd
+	created.ID = s.newID()
+	created.ApplicationID = appID
+	created.GuildID = guildID
+	s.commands[guildID] = slices.DeleteFunc(s.commands[guildID], func(c *discordgo.ApplicationCommand) bool {
+		return c.Type == cmd.Type && c.Name == cmd.Name
+	})
+	s.commands[guildID] = append(s.commands[guildID], &created)
+	return &created, nil
+}
+
+// ApplicationCommandEdit replaces a registered command.
+func (s *Session) ApplicationCommandEdit(appID, guildID, cmdID string, cmd *discordgo.ApplicationCommand, _ ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.commands[guildID], func(c *discordgo.ApplicationCommand) bool { return c.ID == cmdID })
+	if i < 0 {
+		return nil, fmt.Errorf("unknown command %s", cmdID)
+	}
+	edited := *cmd
+	edited.ID = cmdID
+	edited.ApplicationID = appID
+	edited.GuildID = guildID
+	s.commands[guildID][i] = &edited
+	return &edited, nil
+}
+
+// ApplicationCommandDelete removes a registered command.
+func (s *Session) ApplicationCommandDelete(_, guildID, cmdID string, _ ...discordgo.RequestOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.commands[guildID])
+	s.commands[guildID] = slices.DeleteFunc(s.commands[guildID], func(c *discordgo.ApplicationCommand) bool { return c.ID == cmdID })
+	if len(s.commands[guildID]) == n {
+		return fmt.Errorf("unknown command %s", cmdID)
+	}
+	return nil
+}
+
+// ApplicationCommandBulkOverwrite replaces every command registered in the guild, or globally for "".
+func (s *Session) ApplicationCommandBulkOverwrite(appID, guildID string, commands []*discordgo.ApplicationCommand, _ ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var created []*discordgo.ApplicationCommand
+	for _, cmd := range commands {
+		c := *cmd
+		c.ID = s.newID()
+		c.ApplicationID = appID
+		c.GuildID = guildID
+		created = append(created, &c)
+	}
+	s.commands[guildID] = created
+	return slices.Clone(created), nil
+}
+
+// send records a message sent to a channel.
+func (s *Session) send(channelID string, data *discordgo.MessageSend) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	msg := &Message{ID: s.newID(), ChannelID: channelID, MessageSend: data}
+	s.messages = append(s.messages, msg)
+	return &discordgo.Message{
+		ID:        msg.ID,
+		ChannelID: channelID,
+		Content:   data.Content,
+		Embeds:    data.Embeds,
+		Author:    &discordgo.User{ID: s.userID, Bot: true},
+	}, nil
+}
+
+// ChannelMessageSend records a text message.
+func (s *Session) ChannelMessageSend(channelID, content string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return s.send(channelID, &discordgo.MessageSend{Content: content})
+}
+
+// ChannelMessageSendEmbed records an embed message.
+func (s *Session) ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return s.send(channelID, &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}})
+}
+
+// ChannelMessageSendComplex records a message. Its files are recorded as they are; their readers
+// haven't been read.
+func (s *Session) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return s.send(channelID, data)
+}
+
+// ChannelMessageSendReply records a text reply.
+func (s *Session) ChannelMessageSendReply(channelID, content string, reference *discordgo.MessageReference, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return s.send(channelID, &discordgo.MessageSend{Content: content, Reference: reference})
+}
+
+// ChannelMessageSendEmbedReply records an embed reply.
+func (s *Session) ChannelMessageSendEmbedReply(channelID string, embed *discordgo.MessageEmbed, reference *discordgo.MessageReference, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return s.send(channelID, &discordgo.MessageSend{Embeds: []*discordgo.MessageEmbed{embed}, Reference: reference})
+}
+
+// MessageReactionAdd records a reaction.
+func (s *Session) MessageReactionAdd(channelID, messageID, emojiID string, _ ...discordgo.RequestOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reactions = append(s.reactions, Reaction{ChannelID: channelID, MessageID: messageID, Emoji: emojiID})
+	return nil
+}
+
+// interaction returns the record for an interaction, creating it if needed. The caller must hold mu.
+func (s *Session) interaction(i *discordgo.Interaction) *Interaction {
+	record, ok := s.interactions[i.ID]
+	if !ok {
+		record = &Interaction{}
+		s.interactions[i.ID] = record
+	}
+	return record
+}
+
+// InteractionRespond records the initial response to an interaction. Like Discord, it refuses a
+// second one.
+func (s *Session) InteractionRespond(i *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.interaction(i)
+	if len(record.Responses) > 0 {
+		return fmt.Errorf("interaction %s has already been acknowledged", i.ID)
+	}
+	record.Responses = append(record.Responses, resp)
+	return nil
+}
+
+// InteractionResponseEdit records an edit of the initial response.
+func (s *Session) InteractionResponseEdit(i *discordgo.Interaction, edit *discordgo.WebhookEdit, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.interaction(i)
+	if len(record.Responses) == 0 {
+		return nil, fmt.Errorf("interaction %s hasn't been responded to", i.ID)
+	}
+	record.Edits = append(record.Edits, edit)
+	return &discordgo.Message{ID: "response-" + i.ID, ChannelID: i.ChannelID}, nil
+}
+
+// InteractionResponseDelete records that the initial response was deleted.
+func (s *Session) InteractionResponseDelete(i *discordgo.Interaction, _ ...discordgo.RequestOption) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.interaction(i).Deleted = true
+	return nil
+}
+
+// FollowupMessageCreate records a followup message. Like Discord, it refuses followups before the
+// initial response.
+func (s *Session) FollowupMessageCreate(i *discordgo.Interaction, _ bool, data *discordgo.WebhookParams, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.interaction(i)
+	if len(record.Responses) == 0 {
+		return nil, fmt.Errorf("interaction %s hasn't been responded to", i.ID)
+	}
+	record.Followups = append(record.Followups, data)
+	return &discordgo.Message{ID: s.newID(), ChannelID: i.ChannelID, Content: data.Content}, nil
+}
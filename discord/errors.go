@@ -0,0 +1,134 @@
+package discord
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// UserError is an error whose message is safe to show to the user, such as a missing option or
+// an unknown name. Any other error a handler returns is treated as an internal failure: the user
+// only sees a short ID, and the full error is logged against it.
+type UserError struct {
+	// Message is shown to the user.
+	Message string
+	// Err is the underlying cause, if any. It is logged but not shown.
+	Err error
+}
+
+// Error returns the message, followed by the cause if there is one.
+func (e *UserError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause.
+func (e *UserError) Unwrap() error {
+	return e.Err
+}
+
+// ErrPermissionDenied is the cause of the UserError returned for commands the user isn't allowed to run.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrRateLimited is the cause of the UserError returned for commands that are cooling down or
+// already running as many times as they are allowed to.
+var ErrRateLimited = errors.New("rate limited")
+
+// UserErrorf returns a UserError with a formatted message.
+func UserErrorf(format string, args ...any) error {
+	return &UserError{Message: fmt.Sprintf(format, args...)}
+}
+
+// PanicError is the error a handler or schedule that panicked is treated as having returned.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+// Error describes the panic value.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// recovered calls fn, turning a panic into a *PanicError so that a bug in one handler can't
+// take down the whole bot.
+func recovered[T any](fn func() (T, error)) (result T, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &PanicError{Value: p, Stack: debug.Stack()}
+		}
+	}()
+	return fn()
+}
+
+// logPanic logs a panic that escaped an event handler, so that it doesn't crash the bot.
+// It must be deferred directly.
+func logPanic(event string) {
+	if p := recover(); p != nil {
+		slog.Error("recovered from panic", "event", event, "panic", p, "stack", string(debug.Stack()))
+	}
+}
+
+// newErrorID returns a short random ID that ties an error shown to a user to its log entry.
+func newErrorID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// LogError logs a failure with the given message and attributes. Errors the user can fix are
+// logged as warnings; internal failures are logged as errors against a new error ID, which is
+// returned so it can be shown to the user. Panics are logged with their stack trace. Transports
+// other than Discord use it to report failures the same way.
+func LogError(msg string, err error, args ...any) (errorID string) {
+	var uerr *UserError
+	var verr *ValidationError
+	if errors.As(err, &uerr) || errors.As(err, &verr) {
+		slog.Warn(msg, append(args, "error", err)...)
+		return ""
+	}
+
+	errorID = newErrorID()
+	args = append(args, "error_id", errorID, "error", err)
+	var perr *PanicError
+	if errors.As(err, &perr) {
+		args = append(args, "stack", string(perr.Stack))
+	}
+	slog.Error(msg, args...)
+	return errorID
+}
+
+// reportError logs err, as LogError does, and builds the response data telling the user about it.
+// Validation errors list each invalid option as its own embed field, user errors show their
+// message and internal failures only show their error ID.
+func reportError(msg string, err error, args ...any) *discordgo.InteractionResponseData {
+	errorID := LogError(msg, err, args...)
+	if errorID != "" {
+		return errorResponse(fmt.Sprintf("Something went wrong. If it keeps happening, mention error ID `%s`.", errorID))
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		data := errorResponse("Some of the options given are invalid.")
+		for _, f := range verr.Fields {
+			data.Embeds[0].Fields = append(data.Embeds[0].Fields, &discordgo.MessageEmbedField{
+				Name:  f.Field,
+				Value: f.Message,
+			})
+		}
+		return data
+	}
+
+	var uerr *UserError
+	errors.As(err, &uerr)
+	return errorResponse(uerr.Message)
+}
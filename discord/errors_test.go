@@ -0,0 +1,37 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReportError(t *testing.T) {
+	user := fmt.Errorf("lookup: %w", UserErrorf("Unknown target %s.", "zaps"))
+	if got := reportError("failed", user).Embeds[0].Description; got != "Unknown target zaps." {
+		t.Errorf("expected the user error's message, got %q", got)
+	}
+
+	internal := errors.New("unexpected status 500: <html>...</html>")
+	got := reportError("failed", internal).Embeds[0].Description
+	if strings.Contains(got, "html") || !strings.Contains(got, "error ID") {
+		t.Errorf("expected internal details to be replaced by an error ID, got %q", got)
+	}
+}
+
+func TestRecovered(t *testing.T) {
+	_, err := recovered(func() (*Response, error) {
+		var resp *Response
+		return &Response{Content: resp.Content}, nil
+	})
+	var perr *PanicError
+	if !errors.As(err, &perr) || len(perr.Stack) == 0 {
+		t.Fatalf("expected a panic error with a stack, got %v", err)
+	}
+
+	resp, err := recovered(func() (*Response, error) { return &Response{Content: "ok"}, nil })
+	if err != nil || resp.Content != "ok" {
+		t.Errorf("expected the result to pass through, got %v, %v", resp, err)
+	}
+}
@@ -1,26 +1,159 @@
 package discord
 
 import (
+	"fmt"
+
 	"github.com/bwmarrin/discordgo"
-	"github.com/mitchellh/mapstructure"
 )
 
 // Request is a blank interface for the command request definitions.
 type Request interface{}
 
+// maxAutocompleteChoices is the maximum number of choices Discord accepts in an autocomplete response.
+const maxAutocompleteChoices = 25
+
 // Autocomplete is an interface for types that can provide autocomplete suggestions.
 type Autocomplete interface {
-	// Complete takes an input string and returns a list of choices for the option.
-	Complete(input string) ([]*discordgo.ApplicationCommandOptionChoice, error)
+	// Complete takes the name of the focused option and the text typed into it so far,
+	// and returns a list of choices for the option.
+	Complete(option string, input string) ([]*discordgo.ApplicationCommandOptionChoice, error)
+}
+
+// AutocompleteFunc is an adapter that allows an ordinary function to be used as an Autocomplete.
+type AutocompleteFunc func(option string, input string) ([]*discordgo.ApplicationCommandOptionChoice, error)
+
+// Complete calls f(option, input).
+func (f AutocompleteFunc) Complete(option string, input string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	return f(option, input)
 }
 
 // BotFunctionI is the common interface for all bot command functions.
 type BotFunctionI interface {
 	GetName() string
 	GetRequestPrototype() Request
+	// GetConfig returns the optional settings the function was created with.
+	GetConfig() FunctionConfig
+	// GetCommand builds the application command to register with Discord.
+	GetCommand() (*discordgo.ApplicationCommand, error)
 	// HandleInteraction decodes interaction data into a request struct and calls the handler.
+	// The invocation describes who ran the command and where, and carries the response deadline.
 	// It returns the response data that can be sent directly to Discord.
-	HandleInteraction(data *discordgo.ApplicationCommandInteractionData) (*discordgo.InteractionResponseData, error)
+	HandleInteraction(inv *Invocation, data *discordgo.ApplicationCommandInteractionData) (*Response, error)
+	// HandleAutocomplete finds the focused option in the interaction data and returns
+	// the suggestions for it.
+	HandleAutocomplete(data *discordgo.ApplicationCommandInteractionData) ([]*discordgo.ApplicationCommandOptionChoice, error)
+}
+
+// FunctionConfig holds optional settings for a bot function that control how the bot runs it.
+type FunctionConfig struct {
+	// Description is shown in Discord's command picker and in /help.
+	Description string
+	// Category groups the function with related ones in /help.
+	Category string
+	// Examples are sample invocations shown in /help, e.g. "/zaps start:2025/01/01".
+	Examples []string
+	// Defer acknowledges the interaction before the handler runs, so the user immediately sees that
+	// the bot is working. Handlers that take more than a couple of seconds are deferred automatically
+	// either way; this just skips the wait.
+	Defer bool
+	// Policy restricts who can run the function.
+	Policy Policy
+	// Cooldowns limit how often the function can be run.
+	Cooldowns []Cooldown
+	// MaxInFlight limits how many runs of the function can be in progress at once. Zero means no limit.
+	MaxInFlight int
+	// SharedLimits, when set, is the key the function's cooldowns and in-flight runs are counted
+	// under instead of its own name.
+	SharedLimits string
+	// Ephemeral makes responses visible only to the user who ran the command. Handlers can
+	// change this for a single invocation with Invocation.SetEphemeral.
+	Ephemeral bool
+	// Shareable adds a button to ephemeral responses that reposts them publicly.
+	Shareable bool
+	// DMPermission sets whether the function can be used in direct messages with the bot.
+	// When nil Discord's default applies, which allows it. Only global commands appear in DMs.
+	DMPermission *bool
+	// Middleware wraps the function's handler, inside the bot's own middleware and checks.
+	Middleware []Middleware
+}
+
+// FunctionOption is a function that modifies FunctionConfig.
+type FunctionOption func(*FunctionConfig)
+
+// WithDescription sets the description shown in Discord's command picker and in /help.
+func WithDescription(description string) FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.Description = description
+	}
+}
+
+// WithCategory groups the function with related ones in /help.
+func WithCategory(category string) FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.Category = category
+	}
+}
+
+// WithExamples adds sample invocations to the function's /help entry.
+func WithExamples(examples ...string) FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.Examples = append(cfg.Examples, examples...)
+	}
+}
+
+// WithDefer makes the bot defer the response before running the handler,
+// for commands that are known to be slow.
+func WithDefer() FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.Defer = true
+	}
+}
+
+// WithEphemeral makes the function's responses visible only to the user who ran it.
+func WithEphemeral() FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.Ephemeral = true
+	}
+}
+
+// WithShareButton adds a "Share" button to the function's ephemeral responses, which reposts
+// the response publicly in the channel.
+func WithShareButton() FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.Shareable = true
+	}
+}
+
+// WithMiddleware wraps the function's handler in middleware, in the order given. On a group
+// it applies to every subcommand.
+func WithMiddleware(middleware ...Middleware) FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.Middleware = append(cfg.Middleware, middleware...)
+	}
+}
+
+// WithDMPermission sets whether the function can be used in direct messages with the bot.
+func WithDMPermission(allowed bool) FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.DMPermission = &allowed
+	}
+}
+
+// describe returns the configured description, or fallback if there is none.
+func describe(cfg FunctionConfig, fallback string) string {
+	if cfg.Description != "" {
+		return cfg.Description
+	}
+	return fallback
+}
+
+// newFunctionConfig builds a FunctionConfig from the given options.
+func newFunctionConfig(opts []FunctionOption) FunctionConfig {
+	var cfg FunctionConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
 }
 
 // GenericBotFunction is a generic implementation of BotFunctionI.
@@ -31,9 +164,13 @@ type GenericBotFunction[T Request] struct {
 	// used for reflection to generate command options.
 	RequestPrototype T
 	// Handler is the function to execute for the command.
-	Handler func(T) (*discordgo.InteractionResponseData, error)
+	Handler func(T) (*Response, error)
+	// ContextHandler is used instead of Handler when set, and is also given the invocation.
+	ContextHandler func(*Invocation, T) (*Response, error)
 	// Autocomplete is an optional implementation for providing autocomplete choices.
 	Autocomplete Autocomplete
+	// Config holds optional settings controlling how the bot runs the function.
+	Config FunctionConfig
 }
 
 // GetName returns the command's name.
@@ -46,39 +183,84 @@ func (bf *GenericBotFunction[T]) GetRequestPrototype() Request {
 	return bf.RequestPrototype
 }
 
+// GetConfig returns the function's optional settings.
+func (bf *GenericBotFunction[T]) GetConfig() FunctionConfig {
+	return bf.Config
+}
+
+// GetCommand builds the slash command for the function, with options generated from the request prototype.
+func (bf *GenericBotFunction[T]) GetCommand() (*discordgo.ApplicationCommand, error) {
+	options, err := structToCommandOptions(bf.RequestPrototype)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate options for command %s: %w", bf.Name, err)
+	}
+	cmd := &discordgo.ApplicationCommand{
+		Name:        bf.Name,
+		Description: describe(bf.Config, "Auto-generated command for "+bf.Name),
+		Options:     options,
+	}
+	applyConfig(cmd, bf.Config)
+	return cmd, nil
+}
+
 // HandleInteraction processes the interaction by constructing a request of type T from the data
-// and then invoking the handler. It decodes the options using mapstructure and then applies any defaults.
-func (bf *GenericBotFunction[T]) HandleInteraction(data *discordgo.ApplicationCommandInteractionData) (*discordgo.InteractionResponseData, error) {
+// and then invoking the handler. It decodes the options using decodeRequest, which also applies any defaults.
+func (bf *GenericBotFunction[T]) HandleInteraction(inv *Invocation, data *discordgo.ApplicationCommandInteractionData) (*Response, error) {
 	var req T
 
-	// Build a map from option name to its value.
+	// Build a map from option name to its value, resolving users, channels, roles and attachments.
 	optsMap := make(map[string]interface{})
 	for _, opt := range data.Options {
-		optsMap[opt.Name] = opt.Value
+		optsMap[opt.Name] = resolveOption(opt, data.Resolved)
 	}
 
-	// Decode into req using mapstructure with our custom tag.
-	decoderConfig := mapstructure.DecoderConfig{
-		TagName:          "discord",
-		Result:           &req,
-		WeaklyTypedInput: true, // helps convert numbers and booleans automatically.
-	}
-	decoder, err := mapstructure.NewDecoder(&decoderConfig)
+	// Decode into req and apply any defaults.
+	err := decodeRequest(optsMap, &req)
 	if err != nil {
 		return nil, err
 	}
-	err = decoder.Decode(optsMap)
-	if err != nil {
-		return nil, err
+
+	if bf.ContextHandler != nil {
+		return bf.ContextHandler(inv, req)
+	}
+	return bf.Handler(req)
+}
+
+// HandleAutocomplete looks up the option the user is currently typing into and asks the
+// function's Autocomplete implementation for suggestions, trimming them to Discord's limit.
+func (bf *GenericBotFunction[T]) HandleAutocomplete(data *discordgo.ApplicationCommandInteractionData) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	if bf.Autocomplete == nil {
+		return nil, fmt.Errorf("command %s does not support autocomplete", bf.Name)
+	}
+
+	focused := focusedOption(data.Options)
+	if focused == nil {
+		return nil, fmt.Errorf("no focused option in autocomplete for command %s", bf.Name)
+	}
+
+	input := ""
+	if focused.Value != nil {
+		input = fmt.Sprint(focused.Value)
 	}
 
-	// Set default values on fields that are still zero.
-	err = setDefaults(&req)
+	choices, err := bf.Autocomplete.Complete(focused.Name, input)
 	if err != nil {
 		return nil, err
 	}
+	if len(choices) > maxAutocompleteChoices {
+		choices = choices[:maxAutocompleteChoices]
+	}
+	return choices, nil
+}
 
-	return bf.Handler(req)
+// focusedOption returns the option marked as focused in an autocomplete interaction, or nil.
+func focusedOption(options []*discordgo.ApplicationCommandInteractionDataOption) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, opt := range options {
+		if opt.Focused {
+			return opt
+		}
+	}
+	return nil
 }
 
 // NewBotFunction is a generic constructor that creates a new BotFunctionI command handler.
@@ -91,15 +273,32 @@ func (bf *GenericBotFunction[T]) HandleInteraction(data *discordgo.ApplicationCo
 //   - description: Overrides the auto-generated option description with a custom text.
 //   - choices:     Provides a semicolon-separated list of choices in the format "value|Label" for the option.
 //   - default:     Specifies a default value to assign if the field remains unset after decoding.
+//   - autocomplete: Asks Discord for suggestions as the user types; they are provided by the
+//     autocomplete implementation passed to NewBotFunction. Cannot be combined with choices.
 //
 // These tags enable you to customize the generated Discord command options and control default values
-// and allowed choices via mapstructure.
-func NewBotFunction[T Request](name string, handler func(T) (*discordgo.InteractionResponseData, error), autocomplete Autocomplete) BotFunctionI {
+// and allowed choices via mapstructure. Optional settings such as WithDefer are passed as opts.
+func NewBotFunction[T Request](name string, handler func(T) (*Response, error), autocomplete Autocomplete, opts ...FunctionOption) BotFunctionI {
 	var reqPrototype T
 	return &GenericBotFunction[T]{
 		Name:             name,
 		RequestPrototype: reqPrototype,
 		Handler:          handler,
 		Autocomplete:     autocomplete,
+		Config:           newFunctionConfig(opts),
+	}
+}
+
+// NewBotFunctionWithContext is like NewBotFunction, but the handler is also given the Invocation,
+// which identifies the invoking user, member roles, guild, channel and locale, and acts as a
+// context.Context that expires when the interaction can no longer be responded to.
+func NewBotFunctionWithContext[T Request](name string, handler func(*Invocation, T) (*Response, error), autocomplete Autocomplete, opts ...FunctionOption) BotFunctionI {
+	var reqPrototype T
+	return &GenericBotFunction[T]{
+		Name:             name,
+		RequestPrototype: reqPrototype,
+		ContextHandler:   handler,
+		Autocomplete:     autocomplete,
+		Config:           newFunctionConfig(opts),
 	}
 }
@@ -0,0 +1,141 @@
+package discord
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// BotFunctionGroup is a BotFunctionI that exposes other functions as subcommands of a single command,
+// e.g. /zaps summary and /zaps export. A group nested inside another group becomes a subcommand group,
+// e.g. /zaps admin sync. Discord allows at most one level of nesting.
+type BotFunctionGroup struct {
+	// Name is the command (or subcommand group) name.
+	Name string
+	// Functions are the subcommands, dispatched to by name.
+	Functions []BotFunctionI
+	// Config holds optional settings that apply to every subcommand in the group.
+	Config FunctionConfig
+}
+
+// GetName returns the group's name.
+func (g *BotFunctionGroup) GetName() string {
+	return g.Name
+}
+
+// GetRequestPrototype returns nil, as a group takes no options of its own.
+func (g *BotFunctionGroup) GetRequestPrototype() Request {
+	return nil
+}
+
+// GetConfig returns the group's optional settings.
+func (g *BotFunctionGroup) GetConfig() FunctionConfig {
+	return g.Config
+}
+
+// GetCommand builds the command with one subcommand option per function,
+// or a subcommand group option for each nested group.
+func (g *BotFunctionGroup) GetCommand() (*discordgo.ApplicationCommand, error) {
+	var options []*discordgo.ApplicationCommandOption
+	for _, fn := range g.Functions {
+		cmd, err := fn.GetCommand()
+		if err != nil {
+			return nil, err
+		}
+
+		optionType := discordgo.ApplicationCommandOptionSubCommand
+		if _, ok := fn.(*BotFunctionGroup); ok {
+			optionType = discordgo.ApplicationCommandOptionSubCommandGroup
+			for _, sub := range cmd.Options {
+				if sub.Type != discordgo.ApplicationCommandOptionSubCommand {
+					return nil, fmt.Errorf("group %s nests %s too deeply: subcommand groups can only contain subcommands", g.Name, fn.GetName())
+				}
+			}
+		}
+
+		options = append(options, &discordgo.ApplicationCommandOption{
+			Type:        optionType,
+			Name:        cmd.Name,
+			Description: cmd.Description,
+			Options:     cmd.Options,
+		})
+	}
+
+	cmd := &discordgo.ApplicationCommand{
+		Name:        g.Name,
+		Description: describe(g.Config, "Auto-generated command group for "+g.Name),
+		Options:     options,
+	}
+	// Discord only supports permissions on whole commands; subcommand policies are enforced by the bot.
+	applyConfig(cmd, g.Config)
+	return cmd, nil
+}
+
+// HandleInteraction dispatches to the subcommand named in the interaction data.
+func (g *BotFunctionGroup) HandleInteraction(inv *Invocation, data *discordgo.ApplicationCommandInteractionData) (*Response, error) {
+	fn, subData, err := g.dispatch(data)
+	if err != nil {
+		return nil, err
+	}
+	return fn.HandleInteraction(inv, subData)
+}
+
+// HandleAutocomplete dispatches to the subcommand named in the interaction data.
+func (g *BotFunctionGroup) HandleAutocomplete(data *discordgo.ApplicationCommandInteractionData) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	fn, subData, err := g.dispatch(data)
+	if err != nil {
+		return nil, err
+	}
+	return fn.HandleAutocomplete(subData)
+}
+
+// dispatch finds the subcommand selected in the interaction data and returns it along with
+// a copy of the data scoped to that subcommand, so it sees only its own options.
+func (g *BotFunctionGroup) dispatch(data *discordgo.ApplicationCommandInteractionData) (BotFunctionI, *discordgo.ApplicationCommandInteractionData, error) {
+	if len(data.Options) == 0 {
+		return nil, nil, fmt.Errorf("no subcommand given for %s", g.Name)
+	}
+	opt := data.Options[0]
+	if opt.Type != discordgo.ApplicationCommandOptionSubCommand && opt.Type != discordgo.ApplicationCommandOptionSubCommandGroup {
+		return nil, nil, fmt.Errorf("expected a subcommand for %s, got %s", g.Name, opt.Type)
+	}
+
+	for _, fn := range g.Functions {
+		if fn.GetName() == opt.Name {
+			subData := *data
+			subData.Name = opt.Name
+			subData.Options = opt.Options
+			return fn, &subData, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown subcommand %s for %s", opt.Name, g.Name)
+}
+
+// commandChain returns the function invoked by the interaction data followed by each nested
+// subcommand down to the one that handles it, so settings at every level can be applied.
+func commandChain(fn BotFunctionI, data *discordgo.ApplicationCommandInteractionData) []BotFunctionI {
+	chain := []BotFunctionI{fn}
+	for {
+		group, ok := fn.(*BotFunctionGroup)
+		if !ok {
+			return chain
+		}
+		next, nextData, err := group.dispatch(data)
+		if err != nil {
+			return chain
+		}
+		fn, data = next, nextData
+		chain = append(chain, fn)
+	}
+}
+
+// NewBotFunctionGroup creates a command that exposes each of functions as a subcommand.
+// Each function keeps its own request struct and handler; passing another group creates
+// a subcommand group. Options set on the group apply to all of its subcommands.
+func NewBotFunctionGroup(name string, functions []BotFunctionI, opts ...FunctionOption) BotFunctionI {
+	return &BotFunctionGroup{
+		Name:      name,
+		Functions: functions,
+		Config:    newFunctionConfig(opts),
+	}
+}
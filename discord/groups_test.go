@@ -0,0 +1,64 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type exportRequest struct {
+	Format string `discord:"choices:csv|CSV;parquet|Parquet"`
+}
+
+func TestBotFunctionGroup(t *testing.T) {
+	var got exportRequest
+	export := NewBotFunction("export", func(req exportRequest) (*Response, error) {
+		got = req
+		return &Response{}, nil
+	}, nil)
+	sync := NewBotFunction("sync", func(req struct{}) (*Response, error) {
+		return &Response{}, nil
+	}, nil)
+	group := NewBotFunctionGroup("zaps", []BotFunctionI{export, NewBotFunctionGroup("admin", []BotFunctionI{sync})})
+
+	cmd, err := group.GetCommand()
+	if err != nil {
+		t.Fatalf("GetCommand: %v", err)
+	}
+	if len(cmd.Options) != 2 ||
+		cmd.Options[0].Type != discordgo.ApplicationCommandOptionSubCommand ||
+		cmd.Options[0].Options[0].Name != "format" ||
+		cmd.Options[1].Type != discordgo.ApplicationCommandOptionSubCommandGroup ||
+		cmd.Options[1].Options[0].Name != "sync" {
+		t.Fatalf("unexpected command options %+v", cmd.Options)
+	}
+
+	_, err = group.HandleInteraction(&Invocation{}, &discordgo.ApplicationCommandInteractionData{
+		Name: "zaps",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+			Name: "export",
+			Type: discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Name:  "format",
+				Type:  discordgo.ApplicationCommandOptionString,
+				Value: "csv",
+			}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("HandleInteraction: %v", err)
+	}
+	if got.Format != "csv" {
+		t.Fatalf("unexpected request %+v", got)
+	}
+
+	// Groups can only be nested one level deep.
+	tooDeep := NewBotFunctionGroup("top", []BotFunctionI{
+		NewBotFunctionGroup("middle", []BotFunctionI{
+			NewBotFunctionGroup("bottom", []BotFunctionI{sync}),
+		}),
+	})
+	if _, err := tooDeep.GetCommand(); err == nil {
+		t.Fatal("expected an error for groups nested too deeply")
+	}
+}
@@ -0,0 +1,155 @@
+package discord
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// guildState holds what the bot tracks for a guild it has been set up in.
+// It is discarded when the bot leaves the guild.
+type guildState struct {
+	// joinedAt is when the bot set the guild up.
+	joinedAt time.Time
+	// channels are the IDs of the guild's channels and threads, as reported when it was set up
+	// and as they were created since.
+	channels []string
+}
+
+// onGuildCreate sets up a guild the first time Discord reports it, which happens for every guild
+// on connect and for any guild the bot is invited to later: it registers commands and sends the
+// online message. Guilds that come back after an outage or reconnect are left as they are.
+func (b *Bot) onGuildCreate(_ *discordgo.Session, g *discordgo.GuildCreate) {
+	defer logPanic("guild create")
+	if g.Unavailable {
+		return
+	}
+
+	b.mu.Lock()
+	_, known := b.guilds[g.ID]
+	if !known {
+		b.guilds[g.ID] = &guildState{joinedAt: time.Now(), channels: channelIDs(g.Guild)}
+	}
+	b.mu.Unlock()
+
+	if known {
+		slog.Debug("guild available again", "guild", g.ID, "name", g.Name)
+		return
+	}
+
+	slog.Info("setting up guild", "guild", g.ID, "name", g.Name)
+	b.setupGuild(g.ID)
+}
+
+// onGuildDelete forgets a guild the bot has been removed from, including its channels in
+// notification targets, which the bot can no longer post in. Guilds that are only temporarily
+// unavailable because of an outage keep their state.
+func (b *Bot) onGuildDelete(_ *discordgo.Session, g *discordgo.GuildDelete) {
+	defer logPanic("guild delete")
+	if g.Unavailable {
+		slog.Warn("guild unavailable", "guild", g.ID)
+		return
+	}
+
+	b.mu.Lock()
+	state, known := b.guilds[g.ID]
+	delete(b.guilds, g.ID)
+	b.mu.Unlock()
+	b.limits.forgetGuild(g.ID)
+
+	if !known {
+		return
+	}
+	slog.Info("left guild", "guild", g.ID, "joined_at", state.joinedAt)
+	channels := state.channels
+	// discordgo passes on the guild as its state last knew it, which may list channels we missed.
+	if g.BeforeDelete != nil {
+		channels = append(channels, channelIDs(g.BeforeDelete)...)
+	}
+	b.removeTargetChannels(g.ID, channels)
+}
+
+// onChannelCreate records a channel created in a guild, so it is taken out of targets if the
+// bot leaves the guild.
+func (b *Bot) onChannelCreate(_ *discordgo.Session, c *discordgo.ChannelCreate) {
+	defer logPanic("channel create")
+	b.addGuildChannel(c.Channel)
+}
+
+// onThreadCreate records a thread created in a guild, as onChannelCreate does for channels.
+func (b *Bot) onThreadCreate(_ *discordgo.Session, c *discordgo.ThreadCreate) {
+	defer logPanic("thread create")
+	b.addGuildChannel(c.Channel)
+}
+
+// addGuildChannel adds a channel to the state of its guild, if the bot has set the guild up.
+func (b *Bot) addGuildChannel(c *discordgo.Channel) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	state, ok := b.guilds[c.GuildID]
+	if !ok || slices.Contains(state.channels, c.ID) {
+		return
+	}
+	state.channels = append(state.channels, c.ID)
+}
+
+// channelIDs returns the IDs of the guild's channels and active threads.
+func channelIDs(g *discordgo.Guild) []string {
+	var ids []string
+	for _, c := range g.Channels {
+		ids = append(ids, c.ID)
+	}
+	for _, c := range g.Threads {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
+
+// setupGuild registers the bot's commands in a guild and announces that the assistant is online.
+func (b *Bot) setupGuild(guildID string) {
+	// With global commands, any left over from per-guild registration would show up twice.
+	guildCommands := b.commands
+	if b.config.GlobalCommands {
+		guildCommands = nil
+	}
+	err := b.syncCommands(guildID, guildCommands)
+	if err != nil {
+		slog.Error("failed to register guild commands", "guild", guildID, "error", err)
+	}
+
+	targetChannel, err := b.getFirstTextChannel(guildID)
+	if err != nil {
+		slog.Error("failed to find channel for online message", "guild", guildID, "error", err)
+		return
+	}
+	_, err = b.session.ChannelMessageSend(targetChannel, b.onlineMessage())
+	if err != nil {
+		slog.Error("failed to send online message", "guild", guildID, "error", err)
+	}
+}
+
+// onlineMessage lists the available commands and active schedules.
+func (b *Bot) onlineMessage() string {
+	// Build a comma-separated list of command names for the online message.
+	var availableCommands []string
+	for _, fn := range b.functions {
+		availableCommands = append(availableCommands, fn.GetName())
+	}
+
+	// Add schedule names to the message
+	var activeSchedules []string
+	for _, schedule := range b.schedules {
+		activeSchedules = append(activeSchedules, fmt.Sprintf("%s (%s)", schedule.GetName(), schedule.GetCronExpression()))
+	}
+
+	commandsMessage := fmt.Sprintf("Assistant online. Available commands: %s. Use /%s for details.",
+		strings.Join(availableCommands, ", "), helpCommand)
+	if len(activeSchedules) > 0 {
+		commandsMessage += fmt.Sprintf("\nActive schedules: %s", strings.Join(activeSchedules, ", "))
+	}
+	return commandsMessage
+}
@@ -0,0 +1,195 @@
+package discord
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// helpCommand is the name of the built-in help command.
+const helpCommand = "help"
+
+// defaultCategory is the /help category of functions that don't set one.
+const defaultCategory = "General"
+
+// helpColor is the embed color used for help.
+const helpColor = 0x5865F2
+
+// helpRequest optionally names the command to show details for.
+type helpRequest struct {
+	Command string `discord:"optional,autocomplete,description:Command to show details for"`
+}
+
+// helpFunction builds the built-in /help command. Its suggestions depend on who asks for them,
+// so they come from completeCommands, which the bot calls in place of the function's autocomplete.
+func (b *Bot) helpFunction() BotFunctionI {
+	return NewBotFunctionWithContext(helpCommand, b.handleHelp, nil,
+		WithDescription("List the commands or show how to use one"),
+		WithExamples("/help", "/help command:help"),
+		WithEphemeral(),
+	)
+}
+
+// visibleFunctions returns the functions the invocation's policies allow, which are the only
+// ones help mentions.
+func (b *Bot) visibleFunctions(inv *Invocation) []BotFunctionI {
+	var visible []BotFunctionI
+	for _, fn := range b.functions {
+		if b.denyReason(inv, fn) == "" {
+			visible = append(visible, fn)
+		}
+	}
+	return visible
+}
+
+// completeCommands suggests the names of the commands the user can run for /help.
+func (b *Bot) completeCommands(inv *Invocation, data *discordgo.ApplicationCommandInteractionData) []*discordgo.ApplicationCommandOptionChoice {
+	input := ""
+	if focused := focusedOption(data.Options); focused != nil && focused.Value != nil {
+		input = fmt.Sprint(focused.Value)
+	}
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, fn := range b.visibleFunctions(inv) {
+		if strings.HasPrefix(fn.GetName(), input) && len(choices) < maxAutocompleteChoices {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: fn.GetName(), Value: fn.GetName()})
+		}
+	}
+	return choices
+}
+
+// handleHelp lists the commands the user can run by category, or describes a single command.
+func (b *Bot) handleHelp(inv *Invocation, req helpRequest) (*Response, error) {
+	visible := b.visibleFunctions(inv)
+
+	var embed *discordgo.MessageEmbed
+	if req.Command == "" {
+		embed = helpOverview(visible)
+	} else {
+		i := slices.IndexFunc(visible, func(fn BotFunctionI) bool { return fn.GetName() == req.Command })
+		if i < 0 {
+			return nil, UserErrorf("Unknown command %s.", req.Command)
+		}
+		var err error
+		embed, err = helpDetails(visible[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Response{
+		Embeds: []*discordgo.MessageEmbed{embed},
+	}, nil
+}
+
+// helpOverview lists the functions by category.
+func helpOverview(functions []BotFunctionI) *discordgo.MessageEmbed {
+	byCategory := make(map[string][]string)
+	for _, fn := range functions {
+		cfg := fn.GetConfig()
+		category := cfg.Category
+		if category == "" {
+			category = defaultCategory
+		}
+		line := "`/" + fn.GetName() + "`"
+		if cfg.Description != "" {
+			line += " - " + cfg.Description
+		}
+		byCategory[category] = append(byCategory[category], line)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "Commands",
+		Description: "Use `/help command:<name>` for details on a command.",
+		Color:       helpColor,
+	}
+	for _, category := range slices.Sorted(maps.Keys(byCategory)) {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  category,
+			Value: strings.Join(byCategory[category], "\n"),
+		})
+	}
+	return embed
+}
+
+// helpDetails describes a function's options, or each subcommand's options for a group,
+// along with its examples.
+func helpDetails(fn BotFunctionI) (*discordgo.MessageEmbed, error) {
+	cmd, err := fn.GetCommand()
+	if err != nil {
+		return nil, err
+	}
+	cfg := fn.GetConfig()
+
+	embed := &discordgo.MessageEmbed{
+		Title:       "/" + fn.GetName(),
+		Description: cfg.Description,
+		Color:       helpColor,
+	}
+	embed.Fields = helpFields("/"+fn.GetName(), fn, cmd.Options)
+	if len(cfg.Examples) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "Examples",
+			Value: "`" + strings.Join(cfg.Examples, "`\n`") + "`",
+		})
+	}
+	return embed, nil
+}
+
+// helpFields returns a field listing the options of a function, or, for a group, the fields of
+// each of its subcommands. Defaults come from the request struct's tags, as Discord doesn't
+// know about them.
+func helpFields(usage string, fn BotFunctionI, options []*discordgo.ApplicationCommandOption) []*discordgo.MessageEmbedField {
+	if group, ok := fn.(*BotFunctionGroup); ok {
+		var fields []*discordgo.MessageEmbedField
+		for _, opt := range options {
+			sub := slices.IndexFunc(group.Functions, func(f BotFunctionI) bool { return f.GetName() == opt.Name })
+			if sub < 0 {
+				continue
+			}
+			fields = append(fields, helpFields(usage+" "+opt.Name, group.Functions[sub], opt.Options)...)
+		}
+		return fields
+	}
+
+	tags := OptionTags(fn.GetRequestPrototype())
+	var lines []string
+	if description := fn.GetConfig().Description; description != "" && usage != "/"+fn.GetName() {
+		// Subcommands show their own description above their options.
+		lines = append(lines, description)
+	}
+	for _, opt := range options {
+		lines = append(lines, describeOption(opt, tags[opt.Name]["default"]))
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No options.")
+	}
+	return []*discordgo.MessageEmbedField{{
+		Name:  usage,
+		Value: strings.Join(lines, "\n"),
+	}}
+}
+
+// describeOption renders an option as a line of help, e.g.
+// "`color` (string, optional, default: blue) - Favorite color. Choices: red, blue".
+func describeOption(opt *discordgo.ApplicationCommandOption, def string) string {
+	details := []string{strings.ToLower(opt.Type.String())}
+	if !opt.Required {
+		details = append(details, "optional")
+	}
+	if def != "" {
+		details = append(details, "default: "+def)
+	}
+
+	line := fmt.Sprintf("`%s` (%s) - %s", opt.Name, strings.Join(details, ", "), opt.Description)
+	if len(opt.Choices) > 0 {
+		var names []string
+		for _, choice := range opt.Choices {
+			names = append(names, choice.Name)
+		}
+		line += ". Choices: " + strings.Join(names, ", ")
+	}
+	return line
+}
@@ -0,0 +1,91 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type greetRequest struct {
+	Color string `discord:"optional,description:Favorite color,choices:red|Red;blue|Blue,default:blue"`
+}
+
+func TestHelp(t *testing.T) {
+	noop := func(greetRequest) (*Response, error) { return nil, nil }
+	b := &Bot{}
+	b.functions = []BotFunctionI{
+		NewBotFunction("greet", noop, nil,
+			WithDescription("Say hello"), WithCategory("Fun"), WithExamples("/greet color:red")),
+		NewBotFunctionGroup("admin", []BotFunctionI{
+			NewBotFunction("reset", noop, nil, WithDescription("Reset everything")),
+		}, WithPolicy(Policy{AllowedUsers: []string{"admin"}})),
+		b.helpFunction(),
+	}
+	inv := &Invocation{User: &discordgo.User{ID: "alice"}}
+
+	resp, err := b.handleHelp(inv, helpRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	overview := resp.Embeds[0]
+	if len(overview.Fields) != 2 || overview.Fields[0].Name != "Fun" || overview.Fields[1].Name != defaultCategory {
+		t.Fatalf("unexpected categories %+v", overview.Fields)
+	}
+	if strings.Contains(overview.Fields[1].Value, "admin") {
+		t.Error("commands the user can't run should be hidden")
+	}
+
+	resp, err = b.handleHelp(inv, helpRequest{Command: "greet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	details := resp.Embeds[0]
+	want := "`color` (string, optional, default: blue) - Favorite color. Choices: Red, Blue"
+	if details.Fields[0].Value != want {
+		t.Errorf("expected option line %q, got %q", want, details.Fields[0].Value)
+	}
+	if details.Fields[1].Name != "Examples" {
+		t.Errorf("expected examples, got %+v", details.Fields[1])
+	}
+
+	if _, err := b.handleHelp(inv, helpRequest{Command: "admin"}); err == nil {
+		t.Error("expected hidden commands not to be described")
+	}
+	resp, err = b.handleHelp(&Invocation{User: &discordgo.User{ID: "admin"}}, helpRequest{Command: "admin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if field := resp.Embeds[0].Fields[0]; field.Name != "/admin reset" || !strings.HasPrefix(field.Value, "Reset everything") {
+		t.Errorf("unexpected subcommand help %+v", field)
+	}
+}
+
+func TestHelpAutocomplete(t *testing.T) {
+	noop := func(struct{}) (*Response, error) { return nil, nil }
+	greet := NewBotFunction("greet", noop, nil)
+	targets := NewBotFunction("targets", noop, nil, WithPolicy(Policy{AllowedUsers: []string{"operator"}}))
+	_, session := newTestBot(t, []BotFunctionI{greet, targets})
+
+	suggest := func(userID string) []string {
+		i := commandInteraction(userID, discordgo.ApplicationCommandInteractionData{
+			Name: helpCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "command", Type: discordgo.ApplicationCommandOptionString, Value: "", Focused: true},
+			},
+		})
+		i.Type = discordgo.InteractionApplicationCommandAutocomplete
+		var names []string
+		for _, choice := range session.Interact(i).Responses[0].Data.Choices {
+			names = append(names, choice.Name)
+		}
+		return names
+	}
+
+	if names := strings.Join(suggest("alice"), ","); names != "greet,help" {
+		t.Errorf("expected restricted commands to be left out of suggestions, got %s", names)
+	}
+	if names := strings.Join(suggest("operator"), ","); names != "greet,targets,help" {
+		t.Errorf("expected operators to be offered every command, got %s", names)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/mitchellh/mapstructure"
 )
 
 // parseDiscordTag parses a struct tag value (e.g. "optional,description:desc,choices:val1|Label1;val2|Label2,default:foo")
@@ -31,6 +32,28 @@ func parseDiscordTag(tag string) map[string]string {
 	return result
 }
 
+// OptionTags returns the parsed "discord" tag of each field in a request struct, by option name,
+// for describing commands outside of Discord, e.g. their defaults. It returns an empty map if req
+// isn't a struct.
+func OptionTags(req Request) map[string]map[string]string {
+	tags := make(map[string]map[string]string)
+	if req == nil {
+		return tags
+	}
+	t := reflect.TypeOf(req)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return tags
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tags[strings.ToLower(field.Name)] = parseDiscordTag(field.Tag.Get("discord"))
+	}
+	return tags
+}
+
 // parseChoices parses a choices string (e.g. "val1|Label1;val2|Label2")
 // and returns a slice of discordgo.ApplicationCommandOptionChoice.
 func parseChoices(s string) []*discordgo.ApplicationCommandOptionChoice {
@@ -61,6 +84,36 @@ func parseChoices(s string) []*discordgo.ApplicationCommandOptionChoice {
 	return choices
 }
 
+// decodeRequest validates a map of option names to values and decodes it into the struct pointed
+// to by req, then sets defaults on any fields that are still zero. Invalid values are reported
+// as a *ValidationError.
+func decodeRequest(values map[string]interface{}, req interface{}) error {
+	values, err := prepareValues(values, req)
+	if err != nil {
+		return err
+	}
+
+	// Option names are the lower-cased field names, so let mapstructure match keys against field
+	// names case-insensitively. The "discord" tag holds option metadata rather than a key name,
+	// so it must not be used as the decoder's tag.
+	decoderConfig := mapstructure.DecoderConfig{
+		Result:           req,
+		WeaklyTypedInput: true, // helps convert numbers and booleans automatically.
+		DecodeHook:       resolvedIDHook,
+	}
+	decoder, err := mapstructure.NewDecoder(&decoderConfig)
+	if err != nil {
+		return err
+	}
+	err = decoder.Decode(values)
+	if err != nil {
+		return err
+	}
+
+	// Set default values on fields that are still zero.
+	return setDefaults(req)
+}
+
 // setDefaults iterates over the fields of a struct pointed to by req and, if a field is zero,
 // sets it to the default value specified by the "default" key in the "discord" tag.
 func setDefaults(req interface{}) error {
@@ -88,7 +141,13 @@ func setDefaults(req interface{}) error {
 		}
 		tags := parseDiscordTag(tag)
 		if def, ok := tags["default"]; ok && def != "" {
-			converted, err := convertType(def, field.Type)
+			var converted reflect.Value
+			var err error
+			if isTextType(field.Type) {
+				converted, err = parseText(def, field.Type, tags)
+			} else {
+				converted, err = convertType(def, field.Type)
+			}
 			if err != nil {
 				return err
 			}
@@ -133,8 +192,210 @@ func convertType(val string, t reflect.Type) (reflect.Value, error) {
 	}
 }
 
+// Go types that are decoded from the resolved data of an option rather than its raw value.
+var (
+	userType       = reflect.TypeOf((*discordgo.User)(nil))
+	channelType    = reflect.TypeOf((*discordgo.Channel)(nil))
+	roleType       = reflect.TypeOf((*discordgo.Role)(nil))
+	attachmentType = reflect.TypeOf((*discordgo.MessageAttachment)(nil))
+)
+
+// optionTypesByName maps the values of the "type" tag to option types. The tag lets string fields
+// receive the ID of a user, channel, role or mentionable, or the URL of an attachment.
+var optionTypesByName = map[string]discordgo.ApplicationCommandOptionType{
+	"user":        discordgo.ApplicationCommandOptionUser,
+	"channel":     discordgo.ApplicationCommandOptionChannel,
+	"role":        discordgo.ApplicationCommandOptionRole,
+	"mentionable": discordgo.ApplicationCommandOptionMentionable,
+	"attachment":  discordgo.ApplicationCommandOptionAttachment,
+}
+
+// channelTypesByName maps the names accepted by the "channel_types" tag to channel types.
+var channelTypesByName = map[string]discordgo.ChannelType{
+	"text":           discordgo.ChannelTypeGuildText,
+	"dm":             discordgo.ChannelTypeDM,
+	"voice":          discordgo.ChannelTypeGuildVoice,
+	"group_dm":       discordgo.ChannelTypeGroupDM,
+	"category":       discordgo.ChannelTypeGuildCategory,
+	"news":           discordgo.ChannelTypeGuildNews,
+	"news_thread":    discordgo.ChannelTypeGuildNewsThread,
+	"public_thread":  discordgo.ChannelTypeGuildPublicThread,
+	"private_thread": discordgo.ChannelTypeGuildPrivateThread,
+	"stage":          discordgo.ChannelTypeGuildStageVoice,
+	"directory":      discordgo.ChannelTypeGuildDirectory,
+	"forum":          discordgo.ChannelTypeGuildForum,
+	"media":          discordgo.ChannelTypeGuildMedia,
+}
+
+// fieldOptionType returns the option type for a request field, taking the "type" tag into account.
+func fieldOptionType(field reflect.StructField, tags map[string]string) (discordgo.ApplicationCommandOptionType, error) {
+	optionName := strings.ToLower(field.Name)
+
+	if name, ok := tags["type"]; ok {
+		optionType, known := optionTypesByName[name]
+		if !known {
+			return 0, fmt.Errorf("unknown type %q for option %s", name, optionName)
+		}
+		if field.Type.Kind() != reflect.String {
+			return 0, fmt.Errorf("option %s uses type:%s so must be a string, got %s", optionName, name, field.Type)
+		}
+		return optionType, nil
+	}
+
+	// Times, durations and types implementing encoding.TextUnmarshaler are typed in as text.
+	if isTextType(field.Type) {
+		return discordgo.ApplicationCommandOptionString, nil
+	}
+
+	// Map common Go types to Discord option types.
+	switch field.Type {
+	case userType:
+		return discordgo.ApplicationCommandOptionUser, nil
+	case channelType:
+		return discordgo.ApplicationCommandOptionChannel, nil
+	case roleType:
+		return discordgo.ApplicationCommandOptionRole, nil
+	case attachmentType:
+		return discordgo.ApplicationCommandOptionAttachment, nil
+	}
+	switch field.Type.Kind() {
+	case reflect.String:
+		return discordgo.ApplicationCommandOptionString, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return discordgo.ApplicationCommandOptionInteger, nil
+	case reflect.Float32, reflect.Float64:
+		return discordgo.ApplicationCommandOptionNumber, nil
+	case reflect.Bool:
+		return discordgo.ApplicationCommandOptionBoolean, nil
+	default:
+		// Nested structs can't be options; subcommands are built with NewBotFunctionGroup.
+		return 0, fmt.Errorf("unsupported type for option %s: %s", optionName, field.Type)
+	}
+}
+
+// applyConstraints sets the min/max, min_length/max_length and channel_types tags on an option,
+// rejecting tags that don't apply to its type.
+func applyConstraints(opt *discordgo.ApplicationCommandOption, tags map[string]string) error {
+	numeric := opt.Type == discordgo.ApplicationCommandOptionInteger || opt.Type == discordgo.ApplicationCommandOptionNumber
+
+	if min, ok := tags["min"]; ok {
+		if !numeric {
+			return fmt.Errorf("option %s: min only applies to numbers", opt.Name)
+		}
+		v, err := strconv.ParseFloat(min, 64)
+		if err != nil {
+			return fmt.Errorf("option %s: invalid min: %w", opt.Name, err)
+		}
+		opt.MinValue = &v
+	}
+	if max, ok := tags["max"]; ok {
+		if !numeric {
+			return fmt.Errorf("option %s: max only applies to numbers", opt.Name)
+		}
+		v, err := strconv.ParseFloat(max, 64)
+		if err != nil {
+			return fmt.Errorf("option %s: invalid max: %w", opt.Name, err)
+		}
+		opt.MaxValue = v
+	}
+
+	if minLength, ok := tags["min_length"]; ok {
+		if opt.Type != discordgo.ApplicationCommandOptionString {
+			return fmt.Errorf("option %s: min_length only applies to strings", opt.Name)
+		}
+		v, err := strconv.Atoi(minLength)
+		if err != nil {
+			return fmt.Errorf("option %s: invalid min_length: %w", opt.Name, err)
+		}
+		opt.MinLength = &v
+	}
+	if maxLength, ok := tags["max_length"]; ok {
+		if opt.Type != discordgo.ApplicationCommandOptionString {
+			return fmt.Errorf("option %s: max_length only applies to strings", opt.Name)
+		}
+		v, err := strconv.Atoi(maxLength)
+		if err != nil {
+			return fmt.Errorf("option %s: invalid max_length: %w", opt.Name, err)
+		}
+		opt.MaxLength = v
+	}
+
+	if channelTypes, ok := tags["channel_types"]; ok {
+		if opt.Type != discordgo.ApplicationCommandOptionChannel {
+			return fmt.Errorf("option %s: channel_types only applies to channels", opt.Name)
+		}
+		// Channel types are separated by pipes, e.g. "text|voice".
+		for _, name := range strings.Split(channelTypes, "|") {
+			channelType, known := channelTypesByName[strings.TrimSpace(name)]
+			if !known {
+				return fmt.Errorf("option %s: unknown channel type %q", opt.Name, name)
+			}
+			opt.ChannelTypes = append(opt.ChannelTypes, channelType)
+		}
+	}
+
+	return nil
+}
+
+// resolveOption returns the value to decode for an option. Users, channels, roles, mentionables and
+// attachments arrive as IDs, so they are looked up in the resolved data; resolvedIDHook turns them
+// back into an ID (or URL) for string fields.
+func resolveOption(opt *discordgo.ApplicationCommandInteractionDataOption, resolved *discordgo.ApplicationCommandInteractionDataResolved) interface{} {
+	id, ok := opt.Value.(string)
+	if !ok || resolved == nil {
+		return opt.Value
+	}
+
+	switch opt.Type {
+	case discordgo.ApplicationCommandOptionUser:
+		if user, ok := resolved.Users[id]; ok {
+			return user
+		}
+	case discordgo.ApplicationCommandOptionChannel:
+		if channel, ok := resolved.Channels[id]; ok {
+			return channel
+		}
+	case discordgo.ApplicationCommandOptionRole:
+		if role, ok := resolved.Roles[id]; ok {
+			return role
+		}
+	case discordgo.ApplicationCommandOptionMentionable:
+		if user, ok := resolved.Users[id]; ok {
+			return user
+		}
+		if role, ok := resolved.Roles[id]; ok {
+			return role
+		}
+	case discordgo.ApplicationCommandOptionAttachment:
+		if attachment, ok := resolved.Attachments[id]; ok {
+			return attachment
+		}
+	}
+	return opt.Value
+}
+
+// resolvedIDHook is a mapstructure decode hook that lets resolved users, channels and roles be
+// decoded into string fields as their ID, and attachments as their URL.
+func resolvedIDHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to.Kind() != reflect.String {
+		return data, nil
+	}
+	switch v := data.(type) {
+	case *discordgo.User:
+		return v.ID, nil
+	case *discordgo.Channel:
+		return v.ID, nil
+	case *discordgo.Role:
+		return v.ID, nil
+	case *discordgo.MessageAttachment:
+		return v.URL, nil
+	}
+	return data, nil
+}
+
 // structToCommandOptions uses reflection to generate Discord command options from a request struct.
-// It also uses custom struct tags (key "discord") for options like optional, choices, description, and default.
+// It also uses custom struct tags (key "discord") for options like optional, choices, description, default,
+// autocomplete, type, value constraints and validation.
 func structToCommandOptions(req Request) ([]*discordgo.ApplicationCommandOption, error) {
 	t := reflect.TypeOf(req)
 	// If req is a pointer, get the underlying value and type.
@@ -150,47 +411,53 @@ func structToCommandOptions(req Request) ([]*discordgo.ApplicationCommandOption,
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		optionName := strings.ToLower(field.Name)
-		var optionType discordgo.ApplicationCommandOptionType
-
-		// Map common Go types to Discord option types.
-		switch field.Type.Kind() {
-		case reflect.String:
-			optionType = discordgo.ApplicationCommandOptionString
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			optionType = discordgo.ApplicationCommandOptionInteger
-		case reflect.Float32, reflect.Float64:
-			optionType = discordgo.ApplicationCommandOptionNumber
-		case reflect.Bool:
-			optionType = discordgo.ApplicationCommandOptionBoolean
-		default:
-			optionType = discordgo.ApplicationCommandOptionString
+		tags := parseDiscordTag(field.Tag.Get("discord"))
+
+		optionType, err := fieldOptionType(field, tags)
+		if err != nil {
+			return nil, err
+		}
+		err = checkValidationTags(field, tags)
+		if err != nil {
+			return nil, err
 		}
 
 		// Defaults.
 		required := true
 		description := "Auto-generated option for " + optionName
 		var choices []*discordgo.ApplicationCommandOptionChoice
+		autocomplete := false
 
-		// Parse custom struct tag if present.
-		if tagValue := field.Tag.Get("discord"); tagValue != "" {
-			tags := parseDiscordTag(tagValue)
-			if _, ok := tags["optional"]; ok {
-				required = false
-			}
-			if desc, ok := tags["description"]; ok && desc != "" {
-				description = desc
-			}
-			if choicesStr, ok := tags["choices"]; ok && choicesStr != "" {
-				choices = parseChoices(choicesStr)
-			}
+		// Apply the custom struct tag.
+		if _, ok := tags["optional"]; ok {
+			required = false
+		}
+		if desc, ok := tags["description"]; ok && desc != "" {
+			description = desc
+		}
+		if choicesStr, ok := tags["choices"]; ok && choicesStr != "" {
+			choices = parseChoices(choicesStr)
+		}
+		if _, ok := tags["autocomplete"]; ok {
+			autocomplete = true
+		}
+
+		// Discord rejects options that declare both static choices and autocomplete.
+		if autocomplete && len(choices) > 0 {
+			return nil, fmt.Errorf("option %s cannot use both choices and autocomplete", optionName)
 		}
 
 		opt := &discordgo.ApplicationCommandOption{
-			Type:        optionType,
-			Name:        optionName,
-			Description: description,
-			Required:    required,
-			Choices:     choices,
+			Type:         optionType,
+			Name:         optionName,
+			Description:  description,
+			Required:     required,
+			Choices:      choices,
+			Autocomplete: autocomplete,
+		}
+		err = applyConstraints(opt, tags)
+		if err != nil {
+			return nil, err
 		}
 		options = append(options, opt)
 	}
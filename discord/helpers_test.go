@@ -0,0 +1,86 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type richRequest struct {
+	Target  *discordgo.User
+	Channel *discordgo.Channel `discord:"optional,channel_types:text|forum"`
+	RoleID  string             `discord:"optional,type:role"`
+	File    *discordgo.MessageAttachment
+	Count   int    `discord:"min:1,max:10"`
+	Note    string `discord:"optional,min_length:3,max_length:50"`
+}
+
+func TestRichOptions(t *testing.T) {
+	options, err := structToCommandOptions(richRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantTypes := []discordgo.ApplicationCommandOptionType{
+		discordgo.ApplicationCommandOptionUser,
+		discordgo.ApplicationCommandOptionChannel,
+		discordgo.ApplicationCommandOptionRole,
+		discordgo.ApplicationCommandOptionAttachment,
+		discordgo.ApplicationCommandOptionInteger,
+		discordgo.ApplicationCommandOptionString,
+	}
+	for i, want := range wantTypes {
+		if options[i].Type != want {
+			t.Errorf("option %s: expected type %s, got %s", options[i].Name, want, options[i].Type)
+		}
+	}
+	if got := options[1].ChannelTypes; len(got) != 2 || got[1] != discordgo.ChannelTypeGuildForum {
+		t.Errorf("unexpected channel types %v", got)
+	}
+	if options[4].MinValue == nil || *options[4].MinValue != 1 || options[4].MaxValue != 10 {
+		t.Errorf("unexpected min/max %v/%v", options[4].MinValue, options[4].MaxValue)
+	}
+	if options[5].MinLength == nil || *options[5].MinLength != 3 || options[5].MaxLength != 50 {
+		t.Errorf("unexpected lengths %v/%v", options[5].MinLength, options[5].MaxLength)
+	}
+
+	if _, err := structToCommandOptions(struct {
+		Name string `discord:"min:1"`
+	}{}); err == nil {
+		t.Error("expected min on a string option to be rejected")
+	}
+
+	var req richRequest
+	fn := NewBotFunction("rich", func(r richRequest) (*Response, error) {
+		req = r
+		return nil, nil
+	}, nil)
+	_, err = fn.HandleInteraction(&Invocation{}, &discordgo.ApplicationCommandInteractionData{
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{
+			{Name: "target", Type: discordgo.ApplicationCommandOptionUser, Value: "1"},
+			{Name: "roleid", Type: discordgo.ApplicationCommandOptionRole, Value: "2"},
+			{Name: "file", Type: discordgo.ApplicationCommandOptionAttachment, Value: "3"},
+			{Name: "count", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(4)},
+		},
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Users:       map[string]*discordgo.User{"1": {ID: "1", Username: "alice"}},
+			Roles:       map[string]*discordgo.Role{"2": {ID: "2", Name: "finance"}},
+			Attachments: map[string]*discordgo.MessageAttachment{"3": {ID: "3", URL: "https://cdn/report.csv", Size: 42}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Target == nil || req.Target.Username != "alice" {
+		t.Errorf("user not decoded: %+v", req.Target)
+	}
+	if req.RoleID != "2" {
+		t.Errorf("expected role ID 2, got %q", req.RoleID)
+	}
+	if req.File == nil || req.File.URL != "https://cdn/report.csv" || req.File.Size != 42 {
+		t.Errorf("attachment not decoded: %+v", req.File)
+	}
+	if req.Count != 4 {
+		t.Errorf("expected count 4, got %d", req.Count)
+	}
+}
@@ -0,0 +1,113 @@
+package discord
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Invocation describes a single use of a command: who ran it, where it was run from, and how long
+// is left to respond. It is a context.Context whose deadline is when the interaction token expires,
+// and it is cancelled once the handler returns or the bot shuts down, so it can be passed straight
+// to anything that takes a context.
+type Invocation struct {
+	context.Context
+
+	// User is the user who invoked the command, whether in a guild or a DM.
+	User *discordgo.User
+	// Member is the guild member who invoked the command, including their roles.
+	// It is nil when the command was invoked in a DM.
+	Member *discordgo.Member
+	// GuildID is the guild the command was invoked in, or empty in a DM.
+	GuildID string
+	// ChannelID is the channel the command was invoked in.
+	ChannelID string
+	// Locale is the invoking user's client locale.
+	Locale discordgo.Locale
+	// Command is the full name of the command as typed, including any subcommands, e.g. "zaps export".
+	Command string
+	// Data holds the options the command was invoked with.
+	Data *discordgo.ApplicationCommandInteractionData
+
+	transport transport
+	// trusted skips the permission checks, for transports run by the bot's operator.
+	trusted bool
+	// chain is the invoked function followed by each nested subcommand down to the one that handles it.
+	chain []BotFunctionI
+}
+
+// transport is how an invocation answers whoever ran it. Discord interactions answer through a
+// responder; other transports, such as the terminal, provide their own.
+type transport interface {
+	setEphemeral(ephemeral bool)
+	isEphemeral(resp *Response) bool
+	deferResponse() error
+	autoDefer()
+	progress(content string) error
+	followup(resp *Response) error
+}
+
+// newInvocation builds the invocation for an interaction answered through r. The returned cancel
+// function must be called once the interaction has been handled.
+func newInvocation(parent context.Context, r *responder) (*Invocation, context.CancelFunc) {
+	i := r.interaction
+	// The interaction ID records when Discord received it, which is when the token's lifetime starts.
+	// Responses are deferred automatically when handlers are slow, so the token's expiry rather than
+	// the initial three second deadline is the point after which work is wasted.
+	received, err := discordgo.SnowflakeTimestamp(i.ID)
+	if err != nil {
+		received = time.Now()
+	}
+	ctx, cancel := context.WithDeadline(parent, received.Add(tokenLifetime))
+
+	inv := &Invocation{
+		Context:   ctx,
+		Member:    i.Member,
+		User:      i.User,
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		Locale:    i.Locale,
+		transport: r,
+	}
+	// In guilds Discord only fills in the member, which carries the user.
+	if inv.User == nil && i.Member != nil {
+		inv.User = i.Member.User
+	}
+	return inv, cancel
+}
+
+// UserID returns the ID of the invoking user, or empty if it is unknown.
+func (inv *Invocation) UserID() string {
+	if inv.User == nil {
+		return ""
+	}
+	return inv.User.ID
+}
+
+// HasRole reports whether the invoking member has the given role. It is always false in DMs.
+func (inv *Invocation) HasRole(roleID string) bool {
+	return inv.Member != nil && slices.Contains(inv.Member.Roles, roleID)
+}
+
+// Followup sends an additional message for the interaction. Discord only accepts followups once
+// the initial response has been sent, so if the handler hasn't responded yet the response is
+// deferred first and the handler's result will replace the "thinking" message. A nil response sends nothing.
+func (inv *Invocation) Followup(resp *Response) error {
+	return inv.transport.followup(resp)
+}
+
+// Progress shows an interim status message, such as "Fetched page 2 of 5", while the handler is
+// still working. The response is deferred first if necessary, and the handler's result replaces
+// the progress message once it returns.
+func (inv *Invocation) Progress(content string) error {
+	return inv.transport.progress(content)
+}
+
+// SetEphemeral chooses whether the response is visible only to the invoking user, overriding the
+// function's default. Call it before reporting progress or sending followups, as those fix the
+// visibility of the deferred response; a result with a different visibility is sent as a new message.
+func (inv *Invocation) SetEphemeral(ephemeral bool) {
+	inv.transport.setEphemeral(ephemeral)
+}
@@ -0,0 +1,225 @@
+package discord
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// CooldownScope determines who shares a cooldown.
+type CooldownScope int
+
+const (
+	// CooldownPerUser gives each user their own cooldown.
+	CooldownPerUser CooldownScope = iota
+	// CooldownPerGuild shares the cooldown between everyone in a guild. In DMs it applies per user.
+	CooldownPerGuild
+	// CooldownGlobal shares the cooldown between everyone.
+	CooldownGlobal
+)
+
+// Cooldown is the minimum time between runs of a function within a scope.
+type Cooldown struct {
+	Scope    CooldownScope
+	Duration time.Duration
+}
+
+// WithCooldown makes the function wait at least d between runs within the given scope.
+// It can be given more than once, e.g. for both a per-user and a global cooldown.
+func WithCooldown(scope CooldownScope, d time.Duration) FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.Cooldowns = append(cfg.Cooldowns, Cooldown{Scope: scope, Duration: d})
+	}
+}
+
+// WithMaxInFlight limits how many runs of the function can be in progress at once.
+func WithMaxInFlight(n int) FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.MaxInFlight = n
+	}
+}
+
+// WithSharedLimits counts the function's runs under key instead of its own name, so functions and
+// components given the same key share cooldowns and in-flight runs, e.g. a command and the button
+// that re-runs it. Each still applies its own cooldown durations and in-flight limit.
+func WithSharedLimits(key string) FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.SharedLimits = key
+	}
+}
+
+// limitKey identifies what cooldowns and in-flight runs are counted against.
+type limitKey struct {
+	// kind separates commands of different types, components and shared limits, so that e.g. a
+	// context menu command and a slash command with the same name don't share limits.
+	kind string
+	// name is the full name of the command, the name of the component, or the shared key.
+	name string
+}
+
+// cooldownKey identifies a single cooldown: a limit key in a scope, for a user or guild where relevant.
+type cooldownKey struct {
+	limit limitKey
+	scope CooldownScope
+	id    string
+}
+
+// limited is a function or component whose limits are checked.
+type limited struct {
+	key limitKey
+	// label names it in messages to users.
+	label string
+	cfg   FunctionConfig
+}
+
+// chainLimits returns the limits of every function in the command chain, each tracked under the
+// command's type and its full name down to that function, e.g. "zaps export".
+func chainLimits(chain []BotFunctionI) []limited {
+	kind := "command"
+	if menu, ok := chain[0].(BotContextMenuI); ok {
+		kind = "user"
+		if menu.GetType() == discordgo.MessageApplicationCommand {
+			kind = "message"
+		}
+	}
+	limits := make([]limited, len(chain))
+	for i, fn := range chain {
+		name := commandName(chain[:i+1])
+		limits[i] = limited{
+			key:   sharedOr(fn.GetConfig(), limitKey{kind: kind, name: name}),
+			label: "`" + name + "`",
+			cfg:   fn.GetConfig(),
+		}
+	}
+	return limits
+}
+
+// componentLimits returns the limits of a component, tracked under its name.
+func componentLimits(component BotComponentI) []limited {
+	return []limited{{
+		key:   sharedOr(component.GetConfig(), limitKey{kind: "component", name: component.GetName()}),
+		label: "This button",
+		cfg:   component.GetConfig(),
+	}}
+}
+
+// modalLimits returns the limits of a modal, tracked under its name.
+func modalLimits(modal BotModalI) []limited {
+	return []limited{{
+		key:   sharedOr(modal.GetConfig(), limitKey{kind: "modal", name: modal.GetName()}),
+		label: "This form",
+		cfg:   modal.GetConfig(),
+	}}
+}
+
+// sharedOr returns the shared limit key set with WithSharedLimits, or key if there is none.
+func sharedOr(cfg FunctionConfig, key limitKey) limitKey {
+	if cfg.SharedLimits != "" {
+		return limitKey{kind: "shared", name: cfg.SharedLimits}
+	}
+	return key
+}
+
+// limiter enforces the cooldowns and in-flight limits of bot functions and components.
+type limiter struct {
+	mu sync.Mutex
+	// readyAt is when each cooldown next allows a run.
+	readyAt map[cooldownKey]time.Time
+	// inFlight counts the runs in progress for each limit key.
+	inFlight map[limitKey]int
+}
+
+// newLimiter creates an empty limiter.
+func newLimiter() *limiter {
+	return &limiter{
+		readyAt:  make(map[cooldownKey]time.Time),
+		inFlight: make(map[limitKey]int),
+	}
+}
+
+// acquire checks the limits of every function in the command chain, or of a component, as returned
+// by chainLimits or componentLimits. If the invocation may go ahead, it starts the cooldowns, counts
+// the run as in flight and returns a release function to call when the run finishes. Otherwise it
+// returns a message for the user explaining when to try again.
+func (l *limiter) acquire(inv *Invocation, limits []limited) (func(), string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.prune(now)
+
+	// Check everything before recording anything, so a refused run doesn't start any cooldowns.
+	var keys []cooldownKey
+	var durations []time.Duration
+	for _, lim := range limits {
+		for _, cooldown := range lim.cfg.Cooldowns {
+			key := newCooldownKey(lim.key, cooldown.Scope, inv)
+			if wait := l.readyAt[key].Sub(now); wait > 0 {
+				return nil, fmt.Sprintf("%s is cooling down. Try again in %d seconds.", lim.label, int(math.Ceil(wait.Seconds())))
+			}
+			keys = append(keys, key)
+			durations = append(durations, cooldown.Duration)
+		}
+		if lim.cfg.MaxInFlight > 0 && l.inFlight[lim.key] >= lim.cfg.MaxInFlight {
+			return nil, fmt.Sprintf("%s is already running. Try again in a moment.", lim.label)
+		}
+	}
+
+	for i, key := range keys {
+		l.readyAt[key] = now.Add(durations[i])
+	}
+	for _, lim := range limits {
+		l.inFlight[lim.key]++
+	}
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, lim := range limits {
+			l.inFlight[lim.key]--
+			if l.inFlight[lim.key] <= 0 {
+				delete(l.inFlight, lim.key)
+			}
+		}
+	}, ""
+}
+
+// forgetGuild drops the guild-wide cooldowns for a guild the bot has left.
+func (l *limiter) forgetGuild(guildID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key := range l.readyAt {
+		if key.scope == CooldownPerGuild && key.id == guildID {
+			delete(l.readyAt, key)
+		}
+	}
+}
+
+// prune removes cooldowns that have already expired so the map doesn't grow without bound.
+func (l *limiter) prune(now time.Time) {
+	for key, readyAt := range l.readyAt {
+		if !readyAt.After(now) {
+			delete(l.readyAt, key)
+		}
+	}
+}
+
+// newCooldownKey builds the key for a cooldown in the given scope.
+func newCooldownKey(limit limitKey, scope CooldownScope, inv *Invocation) cooldownKey {
+	key := cooldownKey{limit: limit, scope: scope}
+	switch scope {
+	case CooldownPerUser:
+		key.id = inv.UserID()
+	case CooldownPerGuild:
+		key.id = inv.GuildID
+		if key.id == "" {
+			// DMs have no guild, so fall back to the user.
+			key.scope = CooldownPerUser
+			key.id = inv.UserID()
+		}
+	}
+	return key
+}
@@ -0,0 +1,90 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestLimiterCooldown(t *testing.T) {
+	fn := NewBotFunction("slow", func(struct{}) (*Response, error) { return nil, nil }, nil,
+		WithCooldown(CooldownPerUser, time.Minute))
+	chain := []BotFunctionI{fn}
+	alice := &Invocation{GuildID: "guild", User: &discordgo.User{ID: "alice"}}
+	bob := &Invocation{GuildID: "guild", User: &discordgo.User{ID: "bob"}}
+
+	l := newLimiter()
+	release, _ := l.acquire(alice, chainLimits(chain))
+	if release == nil {
+		t.Fatal("first run should be allowed")
+	}
+	release()
+
+	release, wait := l.acquire(alice, chainLimits(chain))
+	if release != nil {
+		t.Fatal("second run should be cooling down")
+	}
+	if !strings.Contains(wait, "60 seconds") {
+		t.Fatalf("unexpected message %q", wait)
+	}
+
+	if release, _ := l.acquire(bob, chainLimits(chain)); release == nil {
+		t.Fatal("other users should have their own cooldown")
+	}
+}
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	fn := NewBotFunction("busy", func(struct{}) (*Response, error) { return nil, nil }, nil,
+		WithMaxInFlight(1))
+	chain := []BotFunctionI{fn}
+	inv := &Invocation{User: &discordgo.User{ID: "alice"}}
+
+	l := newLimiter()
+	release, _ := l.acquire(inv, chainLimits(chain))
+	if release == nil {
+		t.Fatal("first run should be allowed")
+	}
+	if r, _ := l.acquire(inv, chainLimits(chain)); r != nil {
+		t.Fatal("second concurrent run should be refused")
+	}
+	release()
+	if r, _ := l.acquire(inv, chainLimits(chain)); r == nil {
+		t.Fatal("run should be allowed once the first finishes")
+	}
+}
+
+func TestLimiterKeys(t *testing.T) {
+	busy := func(name string, opts ...FunctionOption) BotFunctionI {
+		return NewBotFunction(name, func(struct{}) (*Response, error) { return nil, nil }, nil,
+			append([]FunctionOption{WithMaxInFlight(1)}, opts...)...)
+	}
+	zapsExport := []BotFunctionI{NewBotFunctionGroup("zaps", nil), busy("export")}
+	notesExport := []BotFunctionI{NewBotFunctionGroup("notes", nil), busy("export")}
+	menu := []BotFunctionI{NewUserCommand("export", nil, WithMaxInFlight(1))}
+	inv := &Invocation{User: &discordgo.User{ID: "alice"}}
+
+	l := newLimiter()
+	if r, _ := l.acquire(inv, chainLimits(zapsExport)); r == nil {
+		t.Fatal("first run should be allowed")
+	}
+	// Subcommands of other groups and context menu commands with the same name have their own limits.
+	if r, _ := l.acquire(inv, chainLimits(notesExport)); r == nil {
+		t.Error("a subcommand of another group should not share limits")
+	}
+	if r, _ := l.acquire(inv, chainLimits(menu)); r == nil {
+		t.Error("a context menu command should not share limits with a slash command")
+	}
+
+	// A command and a component with the same shared key count against each other.
+	scrape := busy("scrape", WithSharedLimits("scrape"))
+	refresh := NewBotComponent("refresh", func(struct{}, []string) (*Response, error) { return nil, nil },
+		WithMaxInFlight(1), WithSharedLimits("scrape"))
+	if r, _ := l.acquire(inv, chainLimits([]BotFunctionI{scrape})); r == nil {
+		t.Fatal("first run should be allowed")
+	}
+	if r, wait := l.acquire(inv, componentLimits(refresh)); r != nil || !strings.HasPrefix(wait, "This button") {
+		t.Errorf("expected the component to share the command's limit, got %q", wait)
+	}
+}
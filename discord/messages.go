@@ -0,0 +1,157 @@
+package discord
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// BotMessageHandlerI defines the interface for handlers of plain (non-slash command) messages.
+type BotMessageHandlerI interface {
+	// GetName returns the handler's name, used in logs.
+	GetName() string
+	// Match reports whether the handler wants the message. If it does, it returns the message
+	// with its Text and Matches filled in.
+	Match(m *discordgo.Message, botID string) (*Message, bool)
+	// HandleMessage runs the handler for a matched message.
+	HandleMessage(msg *Message) error
+}
+
+// Message is a message matched by a message handler.
+type Message struct {
+	*discordgo.Message
+	// Text is the content with the prefix or mention that triggered the handler removed and
+	// surrounding whitespace trimmed. For regex handlers it is the whole content.
+	Text string
+	// Matches holds the regex match and its submatches for regex handlers.
+	Matches []string
+
+	session Session
+}
+
+// Reply sends content in the same channel as a reply to the message.
+func (m *Message) Reply(content string) error {
+	_, err := m.session.ChannelMessageSendReply(m.ChannelID, content, m.Reference())
+	return err
+}
+
+// ReplyEmbed sends an embed in the same channel as a reply to the message.
+func (m *Message) ReplyEmbed(embed *discordgo.MessageEmbed) error {
+	_, err := m.session.ChannelMessageSendEmbedReply(m.ChannelID, embed, m.Reference())
+	return err
+}
+
+// React adds a reaction to the message, e.g. "✅".
+func (m *Message) React(emoji string) error {
+	return m.session.MessageReactionAdd(m.ChannelID, m.ID, emoji)
+}
+
+// GenericBotMessageHandler is an implementation of BotMessageHandlerI that pairs a matcher with a handler.
+type GenericBotMessageHandler struct {
+	// Name is the handler's identifier.
+	Name string
+	// Matcher decides whether the handler wants a message, returning the text and any regex matches.
+	Matcher func(content, botID string) (text string, matches []string, ok bool)
+	// Handler is called with each matched message.
+	Handler func(msg *Message) error
+}
+
+// GetName returns the handler's name.
+func (h *GenericBotMessageHandler) GetName() string {
+	return h.Name
+}
+
+// Match runs the matcher against the message content.
+func (h *GenericBotMessageHandler) Match(m *discordgo.Message, botID string) (*Message, bool) {
+	text, matches, ok := h.Matcher(m.Content, botID)
+	if !ok {
+		return nil, false
+	}
+	return &Message{Message: m, Text: text, Matches: matches}, true
+}
+
+// HandleMessage calls the handler.
+func (h *GenericBotMessageHandler) HandleMessage(msg *Message) error {
+	return h.Handler(msg)
+}
+
+// NewPrefixHandler creates a handler for messages starting with prefix, ignoring case,
+// e.g. "note:" for "note: buy milk". The handler gets the rest of the message as Text.
+func NewPrefixHandler(name, prefix string, handler func(msg *Message) error) BotMessageHandlerI {
+	return &GenericBotMessageHandler{
+		Name: name,
+		Matcher: func(content, botID string) (string, []string, bool) {
+			if len(content) < len(prefix) || !strings.EqualFold(content[:len(prefix)], prefix) {
+				return "", nil, false
+			}
+			return strings.TrimSpace(content[len(prefix):]), nil, true
+		},
+		Handler: handler,
+	}
+}
+
+// NewRegexHandler creates a handler for messages matching pattern anywhere in their content.
+// The handler gets the match and its submatches as Matches.
+func NewRegexHandler(name string, pattern *regexp.Regexp, handler func(msg *Message) error) BotMessageHandlerI {
+	return &GenericBotMessageHandler{
+		Name: name,
+		Matcher: func(content, botID string) (string, []string, bool) {
+			matches := pattern.FindStringSubmatch(content)
+			if matches == nil {
+				return "", nil, false
+			}
+			return strings.TrimSpace(content), matches, true
+		},
+		Handler: handler,
+	}
+}
+
+// NewMentionHandler creates a handler for messages that start by mentioning the bot.
+// The handler gets the rest of the message as Text.
+func NewMentionHandler(name string, handler func(msg *Message) error) BotMessageHandlerI {
+	return &GenericBotMessageHandler{
+		Name: name,
+		Matcher: func(content, botID string) (string, []string, bool) {
+			// Clients mention users as either <@id> or, for nicknames, <@!id>.
+			for _, mention := range []string{"<@" + botID + ">", "<@!" + botID + ">"} {
+				if rest, ok := strings.CutPrefix(content, mention); ok {
+					return strings.TrimSpace(rest), nil, true
+				}
+			}
+			return "", nil, false
+		},
+		Handler: handler,
+	}
+}
+
+// WithMessageHandlers registers handlers for plain messages. Each message is given to the first
+// handler that matches it, in the order they were registered.
+func WithMessageHandlers(handlers ...BotMessageHandlerI) BotOption {
+	return func(b *Bot) {
+		b.messageHandlers = append(b.messageHandlers, handlers...)
+	}
+}
+
+// handleMessage passes a message to the first registered handler that matches it. Errors are
+// reported to the author as a reply.
+func (b *Bot) handleMessage(m *discordgo.Message) {
+	for _, handler := range b.messageHandlers {
+		msg, ok := handler.Match(m, b.session.BotUserID())
+		if !ok {
+			continue
+		}
+		msg.session = b.session
+
+		slog.Debug("handling message", "handler", handler.GetName(), "author_id", m.Author.ID, "channel_id", m.ChannelID)
+		_, err := recovered(func() (struct{}, error) { return struct{}{}, handler.HandleMessage(msg) })
+		if err != nil {
+			err = msg.ReplyEmbed(reportError("failed to handle message", err, "handler", handler.GetName()).Embeds[0])
+			if err != nil {
+				slog.Error("failed to reply with message handler error", "handler", handler.GetName(), "error", err)
+			}
+		}
+		return
+	}
+}
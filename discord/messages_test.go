@@ -0,0 +1,48 @@
+package discord
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestMessageHandlerMatch(t *testing.T) {
+	noop := func(*Message) error { return nil }
+	prefix := NewPrefixHandler("note", "note:", noop)
+	regex := NewRegexHandler("coffee", regexp.MustCompile(`(\d+) coffees?`), noop)
+	mention := NewMentionHandler("hello", noop)
+
+	tests := []struct {
+		name    string
+		handler BotMessageHandlerI
+		content string
+		match   bool
+		text    string
+	}{
+		{"prefix", prefix, "note: buy milk", true, "buy milk"},
+		{"prefix ignores case", prefix, "Note: buy milk", true, "buy milk"},
+		{"prefix mismatch", prefix, "notes are great", false, ""},
+		{"regex", regex, "had 3 coffees today", true, "had 3 coffees today"},
+		{"regex mismatch", regex, "had tea", false, ""},
+		{"mention", mention, "<@bot> hi there", true, "hi there"},
+		{"nickname mention", mention, "<@!bot> hi", true, "hi"},
+		{"other mention", mention, "<@someone> hi", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok := tt.handler.Match(&discordgo.Message{Content: tt.content}, "bot")
+			if ok != tt.match {
+				t.Fatalf("expected match=%v, got %v", tt.match, ok)
+			}
+			if ok && msg.Text != tt.text {
+				t.Errorf("expected text %q, got %q", tt.text, msg.Text)
+			}
+		})
+	}
+
+	msg, _ := regex.Match(&discordgo.Message{Content: "had 3 coffees"}, "bot")
+	if len(msg.Matches) != 2 || msg.Matches[1] != "3" {
+		t.Errorf("unexpected submatches %v", msg.Matches)
+	}
+}
@@ -0,0 +1,132 @@
+package discord
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Handler runs a command for an invocation. It is what middleware wraps.
+type Handler func(inv *Invocation) (*Response, error)
+
+// Middleware wraps a Handler with behaviour shared by many commands, such as logging, timing or
+// audit trails. It can inspect the invocation before calling next, change or replace the response
+// afterwards, or return an error without calling next to refuse the command.
+type Middleware func(next Handler) Handler
+
+// Use adds middleware that wraps every command, in the order given. It runs outside the bot's own
+// checks, so it also sees commands that are refused for permissions or rate limits, and applies to
+// commands invoked after it is added.
+func (b *Bot) Use(middleware ...Middleware) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.middleware = append(slices.Clip(b.middleware), middleware...)
+}
+
+// commandHandler builds the handler for the command chain. From the outside in, the function's
+// handler is wrapped in panic recovery, the bot's middleware, the built-in access checks and
+// response settings, and the middleware of each function in the chain, from the command down to
+// the subcommand.
+func (b *Bot) commandHandler(chain []BotFunctionI) Handler {
+	b.mu.Lock()
+	middleware := append([]Middleware{recoverPanics}, b.middleware...)
+	b.mu.Unlock()
+
+	middleware = append(middleware, b.authorizeCommand, b.limitCommand, respondSettings)
+	for _, fn := range chain {
+		middleware = append(middleware, fn.GetConfig().Middleware...)
+	}
+
+	fn := chain[0]
+	h := Handler(func(inv *Invocation) (*Response, error) {
+		return fn.HandleInteraction(inv, inv.Data)
+	})
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// recoverPanics turns a panic in the rest of the chain into a *PanicError.
+func recoverPanics(next Handler) Handler {
+	return func(inv *Invocation) (*Response, error) {
+		return recovered(func() (*Response, error) { return next(inv) })
+	}
+}
+
+// authorizeCommand refuses the command unless every function in the chain allows the invocation.
+func (b *Bot) authorizeCommand(next Handler) Handler {
+	return func(inv *Invocation) (*Response, error) {
+		if !inv.trusted && !b.authorize(inv, inv.chain) {
+			return nil, &UserError{Message: permissionDeniedMessage, Err: ErrPermissionDenied}
+		}
+		return next(inv)
+	}
+}
+
+// limitCommand refuses the command while it is cooling down or already running too many times.
+func (b *Bot) limitCommand(next Handler) Handler {
+	return func(inv *Invocation) (*Response, error) {
+		release, wait := b.limits.acquire(inv, chainLimits(inv.chain))
+		if release == nil {
+			return nil, &UserError{Message: wait, Err: ErrRateLimited}
+		}
+		defer release()
+		return next(inv)
+	}
+}
+
+// respondSettings applies the response settings of the functions in the chain: the visibility,
+// deferring up front or once the handler is slow, and the share button.
+func respondSettings(next Handler) Handler {
+	return func(inv *Invocation) (*Response, error) {
+		r := inv.transport
+		// Set the visibility first, as it has to be chosen when the response is deferred.
+		r.setEphemeral(inChain(inv.chain, func(cfg FunctionConfig) bool { return cfg.Ephemeral }))
+		if inChain(inv.chain, func(cfg FunctionConfig) bool { return cfg.Defer }) {
+			err := r.deferResponse()
+			if err != nil {
+				slog.Error("failed to defer command", "command", inv.Command, "error", err)
+			}
+		} else {
+			r.autoDefer()
+		}
+
+		resp, err := next(inv)
+		if err == nil && inChain(inv.chain, func(cfg FunctionConfig) bool { return cfg.Shareable }) && r.isEphemeral(resp) {
+			addShareButton(resp)
+		}
+		return resp, err
+	}
+}
+
+// LogInvocations logs every command with who ran it, how long it took and whether it failed.
+func LogInvocations() Middleware {
+	return func(next Handler) Handler {
+		return func(inv *Invocation) (*Response, error) {
+			start := time.Now()
+			resp, err := next(inv)
+			args := []any{
+				"command", inv.Command,
+				"user_id", inv.UserID(),
+				"guild", inv.GuildID,
+				"duration", time.Since(start),
+			}
+			if err != nil {
+				args = append(args, "error", err)
+			}
+			slog.Info("command invoked", args...)
+			return resp, err
+		}
+	}
+}
+
+// commandName returns the full name of the command chain as typed, e.g. "zaps admin sync".
+func commandName(chain []BotFunctionI) string {
+	names := make([]string, len(chain))
+	for i, fn := range chain {
+		names[i] = fn.GetName()
+	}
+	return strings.Join(names, " ")
+}
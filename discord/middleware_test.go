@@ -0,0 +1,78 @@
+package discord
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCommandMiddleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(inv *Invocation) (*Response, error) {
+				calls = append(calls, name)
+				return next(inv)
+			}
+		}
+	}
+
+	sub := NewBotFunction("export", func(struct{}) (*Response, error) {
+		calls = append(calls, "handler")
+		return &Response{Content: "done"}, nil
+	}, nil, WithMiddleware(trace("export")))
+	group := NewBotFunctionGroup("zaps", []BotFunctionI{sub}, WithMiddleware(trace("zaps")))
+
+	b := &Bot{limits: newLimiter()}
+	b.Use(trace("bot"))
+
+	data := &discordgo.ApplicationCommandInteractionData{
+		Name:    "zaps",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "export", Type: discordgo.ApplicationCommandOptionSubCommand}},
+	}
+	inv := &Invocation{User: &discordgo.User{ID: "alice"}, Data: data, transport: callTransport{&Call{}}}
+	inv.chain = commandChain(group, data)
+	inv.Command = commandName(inv.chain)
+	if inv.Command != "zaps export" {
+		t.Errorf("unexpected command name %q", inv.Command)
+	}
+
+	resp, err := b.commandHandler(inv.chain)(inv)
+	if err != nil || resp.Content != "done" {
+		t.Fatalf("unexpected result %v, %v", resp, err)
+	}
+	if want := []string{"bot", "zaps", "export", "handler"}; !slices.Equal(calls, want) {
+		t.Errorf("expected middleware to run in order %v, got %v", want, calls)
+	}
+}
+
+func TestCommandMiddlewareRefusal(t *testing.T) {
+	fn := NewBotFunction("admin", func(struct{}) (*Response, error) {
+		t.Error("handler should not run")
+		return nil, nil
+	}, nil, WithPolicy(Policy{AllowedUsers: []string{"bob"}}))
+
+	var seen error
+	b := &Bot{limits: newLimiter()}
+	b.Use(func(next Handler) Handler {
+		return func(inv *Invocation) (*Response, error) {
+			resp, err := next(inv)
+			seen = err
+			return resp, err
+		}
+	})
+
+	inv := &Invocation{User: &discordgo.User{ID: "alice"}, Data: &discordgo.ApplicationCommandInteractionData{}, transport: callTransport{&Call{}}}
+	inv.chain = []BotFunctionI{fn}
+	_, err := b.commandHandler(inv.chain)(inv)
+	var uerr *UserError
+	if !errors.As(err, &uerr) || !strings.Contains(uerr.Message, "permission") {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+	if seen != err {
+		t.Error("bot middleware should see refused commands")
+	}
+}
@@ -0,0 +1,230 @@
+package discord
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// maxModalInputs is the maximum number of text inputs Discord allows in a modal.
+const maxModalInputs = 5
+
+// maxModalLabelLength is the longest label Discord accepts on a text input.
+const maxModalLabelLength = 45
+
+// BotModalI is the common interface for modal forms.
+// A modal is shown by returning the response from Open from a command or component handler,
+// and its submission is routed back by name to HandleSubmit.
+type BotModalI interface {
+	GetName() string
+	GetRequestPrototype() Request
+	// Open builds the response that shows the modal to the user.
+	Open() (*Response, error)
+	// HandleSubmit decodes the submitted text inputs into a request struct and calls the handler.
+	HandleSubmit(data *discordgo.ModalSubmitInteractionData) (*Response, error)
+	// GetConfig returns the optional settings the modal was created with, such as its access policy.
+	GetConfig() FunctionConfig
+}
+
+// GenericBotModal is a generic implementation of BotModalI.
+type GenericBotModal[T Request] struct {
+	// Name is the modal's custom ID, used to route submissions back to it.
+	Name string
+	// Title is shown at the top of the modal.
+	Title string
+	// RequestPrototype is an instance of the request type used for reflection to generate text inputs.
+	RequestPrototype T
+	// Handler is the function to execute when the modal is submitted.
+	Handler func(T) (*Response, error)
+	// Config holds optional settings for the modal. Its policy, cooldowns and in-flight limit
+	// are checked before the handler runs.
+	Config FunctionConfig
+}
+
+// GetName returns the modal's name.
+func (bm *GenericBotModal[T]) GetName() string {
+	return bm.Name
+}
+
+// GetRequestPrototype returns the modal's request prototype.
+func (bm *GenericBotModal[T]) GetRequestPrototype() Request {
+	return bm.RequestPrototype
+}
+
+// GetConfig returns the modal's optional settings.
+func (bm *GenericBotModal[T]) GetConfig() FunctionConfig {
+	return bm.Config
+}
+
+// Open builds the response that shows the modal, with one text input per field of the request struct.
+func (bm *GenericBotModal[T]) Open() (*Response, error) {
+	inputs, err := structToTextInputs(bm.RequestPrototype)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{modal: &discordgo.InteractionResponseData{
+		CustomID:   bm.Name,
+		Title:      bm.Title,
+		Components: inputs,
+	}}, nil
+}
+
+// HandleSubmit processes a modal submission by constructing a request of type T from the text inputs
+// and then invoking the handler. Values are decoded the same way as command options.
+func (bm *GenericBotModal[T]) HandleSubmit(data *discordgo.ModalSubmitInteractionData) (*Response, error) {
+	var req T
+
+	err := decodeRequest(textInputValues(data.Components), &req)
+	if err != nil {
+		return nil, err
+	}
+
+	return bm.Handler(req)
+}
+
+// NewBotModal is a generic constructor that creates a modal form from the request struct T.
+// Each exported field becomes a text input named after the lower-cased field name, and the
+// "discord" struct tag controls how it is rendered. The supported tag options are:
+//
+//   - label:       The text shown above the input. Defaults to the field name.
+//   - placeholder: Hint text shown while the input is empty.
+//   - min_length:  The minimum number of characters that must be entered.
+//   - max_length:  The maximum number of characters that may be entered.
+//   - paragraph:   Renders a multi-line input instead of a single line.
+//   - optional:    Allows the input to be left empty.
+//   - default:     Pre-fills the input, and is assigned if the field is left empty.
+//
+// Submitted values are decoded into T the same way as slash command options. Submissions are
+// routed by name alone, so modals that change data should be given the policy of the command that
+// opens them with WithPolicy.
+func NewBotModal[T Request](name string, title string, handler func(T) (*Response, error), opts ...FunctionOption) BotModalI {
+	var reqPrototype T
+	return &GenericBotModal[T]{
+		Name:             name,
+		Title:            title,
+		RequestPrototype: reqPrototype,
+		Handler:          handler,
+		Config:           newFunctionConfig(opts),
+	}
+}
+
+// runModal calls the modal's handler if its policy allows the invocation and it isn't cooling
+// down or already running too many times, as components are checked.
+func (b *Bot) runModal(inv *Invocation, modal BotModalI, data *discordgo.ModalSubmitInteractionData) (*Response, error) {
+	if !b.allowed(inv, modal) {
+		return nil, &UserError{Message: permissionDeniedMessage, Err: ErrPermissionDenied}
+	}
+	release, wait := b.limits.acquire(inv, modalLimits(modal))
+	if release == nil {
+		return nil, &UserError{Message: wait, Err: ErrRateLimited}
+	}
+	defer release()
+	return modal.HandleSubmit(data)
+}
+
+// structToTextInputs uses reflection to generate modal text inputs from a request struct,
+// each wrapped in its own actions row as Discord requires.
+func structToTextInputs(req Request) ([]discordgo.MessageComponent, error) {
+	t := reflect.TypeOf(req)
+	// If req is a pointer, get the underlying type.
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("request is not a struct")
+	}
+	if t.NumField() > maxModalInputs {
+		return nil, fmt.Errorf("modal has %d fields, the limit is %d", t.NumField(), maxModalInputs)
+	}
+
+	var rows []discordgo.MessageComponent
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Float32, reflect.Float64:
+		default:
+			if isTextType(field.Type) {
+				break
+			}
+			return nil, fmt.Errorf("unsupported type for modal field %s: %s", field.Name, field.Type.Kind())
+		}
+
+		input := discordgo.TextInput{
+			CustomID: strings.ToLower(field.Name),
+			Label:    field.Name,
+			Style:    discordgo.TextInputShort,
+			Required: true,
+		}
+
+		// Parse custom struct tag if present.
+		if tagValue := field.Tag.Get("discord"); tagValue != "" {
+			tags := parseDiscordTag(tagValue)
+			if label, ok := tags["label"]; ok && label != "" {
+				input.Label = label
+			}
+			if placeholder, ok := tags["placeholder"]; ok {
+				input.Placeholder = placeholder
+			}
+			if _, ok := tags["paragraph"]; ok {
+				input.Style = discordgo.TextInputParagraph
+			}
+			if _, ok := tags["optional"]; ok {
+				input.Required = false
+			}
+			if def, ok := tags["default"]; ok {
+				input.Value = def
+			}
+			if minLength, ok := tags["min_length"]; ok {
+				n, err := strconv.Atoi(minLength)
+				if err != nil {
+					return nil, fmt.Errorf("invalid min_length for field %s: %w", field.Name, err)
+				}
+				input.MinLength = n
+			}
+			if maxLength, ok := tags["max_length"]; ok {
+				n, err := strconv.Atoi(maxLength)
+				if err != nil {
+					return nil, fmt.Errorf("invalid max_length for field %s: %w", field.Name, err)
+				}
+				input.MaxLength = n
+			}
+		}
+
+		if len(input.Label) > maxModalLabelLength {
+			return nil, fmt.Errorf("label for field %s is longer than %d characters", field.Name, maxModalLabelLength)
+		}
+
+		rows = append(rows, discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{input},
+		})
+	}
+
+	return rows, nil
+}
+
+// textInputValues collects the values of every text input in a modal submission, keyed by custom ID.
+// Empty inputs are left out so that defaults apply to them.
+func textInputValues(components []discordgo.MessageComponent) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, component := range components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range row.Components {
+			input, ok := c.(*discordgo.TextInput)
+			if !ok || input.Value == "" {
+				continue
+			}
+			values[input.CustomID] = input.Value
+		}
+	}
+	return values
+}
@@ -0,0 +1,92 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type noteForm struct {
+	Title string `discord:"label:Title,max_length:100"`
+	Body  string `discord:"paragraph,optional,default:(empty)"`
+}
+
+func TestModalOpenAndSubmit(t *testing.T) {
+	var got noteForm
+	modal := NewBotModal("note", "New note", func(req noteForm) (*Response, error) {
+		got = req
+		return &Response{}, nil
+	})
+
+	resp, err := modal.Open()
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data := resp.interactionData()
+	if data.CustomID != "note" || data.Title != "New note" || len(data.Components) != 2 {
+		t.Fatalf("unexpected modal data %+v", data)
+	}
+	body := data.Components[1].(discordgo.ActionsRow).Components[0].(discordgo.TextInput)
+	if body.CustomID != "body" || body.Style != discordgo.TextInputParagraph || body.Required {
+		t.Fatalf("unexpected body input %+v", body)
+	}
+
+	// Submissions arrive as pointers after being unmarshalled by discordgo.
+	_, err = modal.HandleSubmit(&discordgo.ModalSubmitInteractionData{
+		CustomID: "note",
+		Components: []discordgo.MessageComponent{
+			&discordgo.ActionsRow{Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: "title", Value: "Shopping"}}},
+			&discordgo.ActionsRow{Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: "body"}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("HandleSubmit: %v", err)
+	}
+	if got.Title != "Shopping" || got.Body != "(empty)" {
+		t.Fatalf("unexpected request %+v", got)
+	}
+}
+
+func TestCommandOpensModal(t *testing.T) {
+	modal := NewBotModal("note", "New note", func(req noteForm) (*Response, error) { return &Response{}, nil })
+	open := NewBotFunction("note", func(struct{}) (*Response, error) { return modal.Open() }, nil)
+	_, session := newTestBot(t, []BotFunctionI{open}, WithModals(modal))
+
+	record := session.Interact(commandInteraction("alice", discordgo.ApplicationCommandInteractionData{Name: "note"}))
+	if len(record.Responses) != 1 || record.Responses[0].Type != discordgo.InteractionResponseModal {
+		t.Fatalf("expected the modal to be opened, got %+v", record.Responses)
+	}
+	if data := record.Responses[0].Data; data.CustomID != "note" || data.Flags != 0 {
+		t.Errorf("unexpected modal data %+v", data)
+	}
+}
+
+func TestModalPolicy(t *testing.T) {
+	modal := NewBotModal("note", "New note", func(req noteForm) (*Response, error) {
+		return &Response{Content: "saved " + req.Title}, nil
+	}, WithPolicy(Policy{AllowedUsers: []string{"alice"}}))
+	_, session := newTestBot(t, nil, WithModals(modal))
+
+	submit := func(userID string) *discordgo.InteractionResponseData {
+		record := session.Interact(&discordgo.Interaction{
+			Type:      discordgo.InteractionModalSubmit,
+			GuildID:   "g1",
+			ChannelID: "general",
+			Member:    &discordgo.Member{User: &discordgo.User{ID: userID}},
+			Data: discordgo.ModalSubmitInteractionData{
+				CustomID: "note",
+				Components: []discordgo.MessageComponent{
+					&discordgo.ActionsRow{Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: "title", Value: "Shopping"}}},
+				},
+			},
+		})
+		return record.Responses[0].Data
+	}
+
+	if data := submit("bob"); len(data.Embeds) != 1 || data.Embeds[0].Description != permissionDeniedMessage {
+		t.Errorf("expected bob's submission to be refused, got %+v", data)
+	}
+	if data := submit("alice"); data.Content != "saved Shopping" {
+		t.Errorf("expected alice's submission to be handled, got %+v", data)
+	}
+}
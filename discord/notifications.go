@@ -1,7 +1,9 @@
 package discord
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 
 	"log/slog"
 
@@ -24,42 +26,124 @@ func (b *Bot) getFirstTextChannel(guildID string) (string, error) {
 	return "", fmt.Errorf("no text channel found in guild %s", guildID)
 }
 
-// SendMessage broadcasts a plain text message to the first available text channel
-// in every guild that the bot is currently in.
-func (b *Bot) SendMessage(content string) {
-	// Iterate over all guilds in the bot's state.
-	for _, guild := range b.session.State.Guilds {
-		targetChannel, err := b.getFirstTextChannel(guild.ID)
+// targetChannels returns the IDs of the channels a message to the named target should be posted in,
+// opening DM channels for the target's users and subscribers. An unconfigured default target
+// resolves to the first text channel of every guild the bot is in.
+func (b *Bot) targetChannels(name string) ([]string, error) {
+	name, target, ok := b.lookupTarget(name)
+	if !ok && name != DefaultTarget {
+		return nil, fmt.Errorf("unknown notification target %s", name)
+	}
+
+	if !ok {
+		var channels []string
+		for _, guild := range b.session.Guilds() {
+			targetChannel, err := b.getFirstTextChannel(guild.ID)
+			if err != nil {
+				slog.Error("Error getting text channel", "guild", guild.ID, "error", err)
+				continue
+			}
+			channels = append(channels, targetChannel)
+		}
+		return channels, nil
+	}
+
+	users := target.Users
+	if target.Subscribable && b.subscriptions != nil {
+		subscribers, err := b.subscriptions.subscribers(name)
 		if err != nil {
-			slog.Error("Error getting text channel", "guild", guild.ID, "error", err)
-			continue
+			slog.Error("Error getting subscribers", "target", name, "error", err)
+		}
+		for _, userID := range subscribers {
+			if !slices.Contains(users, userID) {
+				users = append(slices.Clip(users), userID)
+			}
 		}
+	}
 
-		msg, err := b.session.ChannelMessageSend(targetChannel, content)
+	channels := append([]string(nil), target.Channels...)
+	for _, userID := range users {
+		dm, err := b.session.UserChannelCreate(userID)
 		if err != nil {
-			slog.Error("Failed to send message", "guild", guild.ID, "channel", targetChannel, "error", err)
-		} else {
-			slog.Info("Message sent", "guild", guild.ID, "channel", targetChannel, "content", msg.Content)
+			slog.Error("Error opening DM channel", "target", name, "user_id", userID, "error", err)
+			continue
 		}
+		channels = append(channels, dm.ID)
 	}
+	return channels, nil
 }
 
-// SendEmbed broadcasts an embed message to the first available text channel
-// in every guild that the bot is currently in.
-func (b *Bot) SendEmbed(embed *discordgo.MessageEmbed) {
-	// Iterate over all guilds in the bot's state.
-	for _, guild := range b.session.State.Guilds {
-		targetChannel, err := b.getFirstTextChannel(guild.ID)
+// sendTo calls send for each channel of the named target, logging and collecting any failures.
+func (b *Bot) sendTo(name string, send func(channelID string) error) error {
+	if b.session == nil {
+		return errors.New("bot is not connected to Discord")
+	}
+	channels, err := b.targetChannels(name)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, channelID := range channels {
+		err := send(channelID)
 		if err != nil {
-			slog.Error("Error getting text channel", "guild", guild.ID, "error", err)
+			slog.Error("Failed to send notification", "target", name, "channel", channelID, "error", err)
+			errs = append(errs, fmt.Errorf("channel %s: %w", channelID, err))
 			continue
 		}
+		slog.Info("Notification sent", "target", name, "channel", channelID)
+	}
+	return errors.Join(errs...)
+}
 
-		_, err = b.session.ChannelMessageSendEmbed(targetChannel, embed)
-		if err != nil {
-			slog.Error("Failed to send embed", "guild", guild.ID, "channel", targetChannel, "error", err)
-		} else {
-			slog.Info("Embed sent", "guild", guild.ID, "channel", targetChannel)
+// SendMessageTo sends a plain text message to every channel and user of the named notification target.
+func (b *Bot) SendMessageTo(target string, content string) error {
+	return b.sendTo(target, func(channelID string) error {
+		_, err := b.session.ChannelMessageSend(channelID, content)
+		return err
+	})
+}
+
+// SendEmbedTo sends an embed message to every channel and user of the named notification target.
+func (b *Bot) SendEmbedTo(target string, embed *discordgo.MessageEmbed) error {
+	return b.sendTo(target, func(channelID string) error {
+		_, err := b.session.ChannelMessageSendEmbed(channelID, embed)
+		return err
+	})
+}
+
+// SendTo sends a response, including any files, to every channel and user of the named notification target.
+// Ephemeral is ignored, as notifications aren't a reply to anyone. Each page of a paginated response
+// is sent as its own message, so none of them expire. A nil response sends nothing.
+func (b *Bot) SendTo(target string, resp *Response) error {
+	pages, err := eachPage(resp)
+	if err != nil || len(pages) == 0 {
+		return err
+	}
+	return b.sendTo(target, func(channelID string) error {
+		for _, page := range pages {
+			// Each send gets its own file readers.
+			_, err := b.session.ChannelMessageSendComplex(channelID, page.messageSend())
+			if err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+}
+
+// SendMessage sends a plain text message to the default notification target.
+func (b *Bot) SendMessage(content string) {
+	err := b.SendMessageTo(DefaultTarget, content)
+	if err != nil {
+		slog.Error("Failed to send message", "error", err)
+	}
+}
+
+// SendEmbed sends an embed message to the default notification target.
+func (b *Bot) SendEmbed(embed *discordgo.MessageEmbed) {
+	err := b.SendEmbedTo(DefaultTarget, embed)
+	if err != nil {
+		slog.Error("Failed to send embed", "error", err)
 	}
 }
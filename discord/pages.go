@@ -0,0 +1,395 @@
+package discord
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// pageComponent is the name of the previous and next buttons of paginated responses.
+const pageComponent = "pages"
+
+// pageJumpComponent is the name of the button that asks which page to jump to.
+const pageJumpComponent = "pages_jump"
+
+// pageJumpInput is the custom ID of the text input in the jump modal.
+const pageJumpInput = "page"
+
+// defaultPageTimeout is how long the pages of a paginated response are kept after they were last viewed.
+const defaultPageTimeout = time.Hour
+
+// pageList is the set of pages of a paginated response.
+type pageList struct {
+	count int
+	fetch func(page int) (*Response, error)
+}
+
+// Paginate returns a response that shows one of pages at a time, with buttons to move to the
+// previous and next pages and to jump to a page by number. A single page is shown without buttons.
+// Components and Ephemeral set on the returned response apply to every page.
+//
+// Paginated responses can be returned from commands, components and modal submissions, and only
+// users allowed by the policy of whatever returned them can move between pages. The bot keeps the
+// pages until they haven't been viewed for the timeout set with WithPageTimeout; after that the
+// buttons report that the pages have expired. Schedules and SendTo send each page as its own
+// message instead, and transports other than Discord show every page at once.
+func Paginate(pages ...*Response) *Response {
+	return PaginateFunc(len(pages), func(page int) (*Response, error) {
+		return pages[page], nil
+	})
+}
+
+// PaginateFunc is like Paginate, but each of count pages is built by fetch when it is shown.
+// Pages are numbered from zero.
+func PaginateFunc(count int, fetch func(page int) (*Response, error)) *Response {
+	return &Response{pages: &pageList{count: count, fetch: fetch}}
+}
+
+// WithPageTimeout sets how long the pages of paginated responses are kept after they were last
+// viewed. The default is an hour.
+func WithPageTimeout(timeout time.Duration) BotOption {
+	return func(b *Bot) {
+		b.pages.timeout = timeout
+	}
+}
+
+// pageButtonState is the state carried in the custom IDs of navigation buttons.
+type pageButtonState struct {
+	// ID identifies the stored pages.
+	ID string
+	// Page is the page the button shows.
+	Page int
+}
+
+// storedPages is a paginated response kept so its buttons can show other pages.
+type storedPages struct {
+	*pageList
+	// components are shown under every page.
+	components []discordgo.MessageComponent
+	ephemeral  bool
+	// guards are the functions, component or modal that returned the pages, whose policies also
+	// apply to viewing them.
+	guards []guarded
+	// expires is when the pages are forgotten unless viewed again, guarded by the store's mu.
+	expires time.Time
+}
+
+// pageStore keeps the pages of paginated responses until they expire.
+type pageStore struct {
+	mu      sync.Mutex
+	timeout time.Duration
+	lists   map[string]*storedPages
+}
+
+// newPageStore creates an empty pageStore with the default timeout.
+func newPageStore() *pageStore {
+	return &pageStore{
+		timeout: defaultPageTimeout,
+		lists:   make(map[string]*storedPages),
+	}
+}
+
+// add stores pages under a new ID, forgetting any that have expired.
+func (s *pageStore) add(pages *storedPages) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for id, p := range s.lists {
+		if now.After(p.expires) {
+			delete(s.lists, id)
+		}
+	}
+	id := rand.Text()
+	pages.expires = now.Add(s.timeout)
+	s.lists[id] = pages
+	return id
+}
+
+// get returns the pages stored under id, extending their expiry, or false if they have expired.
+func (s *pageStore) get(id string) (*storedPages, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pages, ok := s.lists[id]
+	if !ok {
+		return nil, false
+	}
+	now := time.Now()
+	if now.After(pages.expires) {
+		delete(s.lists, id)
+		return nil, false
+	}
+	pages.expires = now.Add(s.timeout)
+	return pages, true
+}
+
+// renderPages stores a paginated response and returns its first page with navigation buttons.
+// Only users allowed by the policies of guards, which returned the response, can move to other pages.
+// Other responses are returned as they are.
+func (b *Bot) renderPages(resp *Response, guards ...guarded) (*Response, error) {
+	if resp == nil || resp.pages == nil {
+		return resp, nil
+	}
+	pages := &storedPages{pageList: resp.pages, components: resp.Components, ephemeral: resp.Ephemeral, guards: guards}
+	if pages.count == 0 {
+		return &Response{Content: "Nothing to show.", Components: pages.components, Ephemeral: pages.ephemeral}, nil
+	}
+	id := ""
+	if pages.count > 1 {
+		id = b.pages.add(pages)
+	}
+	return pages.page(id, 0)
+}
+
+// page builds page n with the navigation buttons for the pages stored under id. Without an ID
+// there is only one page, and no buttons are added.
+func (p *storedPages) page(id string, n int) (*Response, error) {
+	if n < 0 || n >= p.count {
+		return nil, UserErrorf("Enter a page number from 1 to %d.", p.count)
+	}
+	page, err := p.fetch(n)
+	if err != nil {
+		return nil, err
+	}
+	if page == nil {
+		page = &Response{}
+	}
+	if page.pages != nil || page.modal != nil {
+		return nil, fmt.Errorf("page %d of a paginated response must be a plain response", n)
+	}
+
+	// Copy the page, as pages are shown many times.
+	shown := *page
+	shown.Ephemeral = shown.Ephemeral || p.ephemeral
+	shown.Components = slices.Clone(page.Components)
+	if id != "" {
+		nav, err := p.navigation(id, n)
+		if err != nil {
+			return nil, err
+		}
+		shown.Components = append(shown.Components, nav)
+	}
+	for _, c := range p.components {
+		if len(shown.Components) < maxActionRows {
+			shown.Components = append(shown.Components, c)
+		}
+	}
+	return &shown, nil
+}
+
+// navigation builds the row of buttons for moving from page n to other pages.
+func (p *storedPages) navigation(id string, n int) (discordgo.MessageComponent, error) {
+	prevID, err := CustomID(pageComponent, pageButtonState{ID: id, Page: n - 1})
+	if err != nil {
+		return nil, err
+	}
+	nextID, err := CustomID(pageComponent, pageButtonState{ID: id, Page: n + 1})
+	if err != nil {
+		return nil, err
+	}
+	jumpID, err := CustomID(pageJumpComponent, pageButtonState{ID: id})
+	if err != nil {
+		return nil, err
+	}
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Previous",
+				Style:    discordgo.SecondaryButton,
+				CustomID: prevID,
+				Disabled: n == 0,
+			},
+			discordgo.Button{
+				Label:    fmt.Sprintf("Page %d of %d", n+1, p.count),
+				Style:    discordgo.SecondaryButton,
+				CustomID: jumpID,
+			},
+			discordgo.Button{
+				Label:    "Next",
+				Style:    discordgo.SecondaryButton,
+				CustomID: nextID,
+				Disabled: n == p.count-1,
+			},
+		},
+	}, nil
+}
+
+// allPages merges every page of a paginated response into one, for transports without buttons.
+// Other responses are returned as they are.
+func allPages(resp *Response) (*Response, error) {
+	if resp == nil || resp.pages == nil {
+		return resp, nil
+	}
+	merged := &Response{Components: resp.Components, Ephemeral: resp.Ephemeral}
+	var content []string
+	for n := range resp.pages.count {
+		page, err := resp.pages.fetch(n)
+		if err != nil {
+			return nil, err
+		}
+		if page == nil {
+			continue
+		}
+		if page.Content != "" {
+			content = append(content, page.Content)
+		}
+		merged.Embeds = append(merged.Embeds, page.Embeds...)
+		merged.Files = append(merged.Files, page.Files...)
+	}
+	merged.Content = strings.Join(content, "\n")
+	return merged, nil
+}
+
+// eachPage returns every page of a paginated response, with the response's components, for
+// messages that nobody may be around to page through before the pages expire. Other responses
+// are returned as the only page, and a nil response has no pages.
+func eachPage(resp *Response) ([]*Response, error) {
+	if resp == nil {
+		return nil, nil
+	}
+	if resp.pages == nil {
+		return []*Response{resp}, nil
+	}
+	pages := &storedPages{pageList: resp.pages, components: resp.Components, ephemeral: resp.Ephemeral}
+	if pages.count == 0 {
+		return []*Response{{Content: "Nothing to show.", Components: pages.components}}, nil
+	}
+	shown := make([]*Response, 0, pages.count)
+	for n := range pages.count {
+		page, err := pages.page("", n)
+		if err != nil {
+			return nil, err
+		}
+		shown = append(shown, page)
+	}
+	return shown, nil
+}
+
+// viewPages returns the pages stored under id, if they haven't expired and the invocation is
+// allowed by the policies of whatever returned them.
+func (b *Bot) viewPages(inv *Invocation, id string) (*storedPages, error) {
+	pages, ok := b.pages.get(id)
+	if !ok {
+		return nil, UserErrorf("These pages have expired. Run the command again to see them.")
+	}
+	for _, g := range pages.guards {
+		if !b.allowed(inv, g) {
+			return nil, &UserError{Message: permissionDeniedMessage, Err: ErrPermissionDenied}
+		}
+	}
+	return pages, nil
+}
+
+// pageResponse answers a navigation interaction by replacing the message with the requested page.
+// Failures, including expired pages, are sent as a new message so the current page stays.
+func (b *Bot) pageResponse(i *discordgo.InteractionCreate, state pageButtonState, err error) {
+	r := newResponder(b.session, i.Interaction, discordgo.InteractionResponseUpdateMessage)
+	r.autoDefer()
+	inv, cancel := newInvocation(b.ctx, r)
+	defer cancel()
+
+	var resp *Response
+	if err == nil {
+		resp, err = recovered(func() (*Response, error) {
+			pages, err := b.viewPages(inv, state.ID)
+			if err != nil {
+				return nil, err
+			}
+			return pages.page(state.ID, state.Page)
+		})
+	}
+	if err != nil {
+		err = r.respondError(reportError("failed to show page", err, "page", state.Page+1))
+		if err != nil {
+			slog.Error("failed to send page error", "error", err)
+		}
+		return
+	}
+
+	err = r.respond(resp.interactionData(), false)
+	if err != nil {
+		slog.Error("failed to show page", "page", state.Page+1, "error", err)
+	}
+}
+
+// parsePageButtonState decodes the state in the custom ID of a navigation button or jump modal.
+func parsePageButtonState(customID string) (pageButtonState, error) {
+	var state pageButtonState
+	_, values, err := parseCustomID(customID)
+	if err != nil {
+		return state, err
+	}
+	err = decodeRequest(values, &state)
+	return state, err
+}
+
+// handlePage shows the page named by a previous or next button.
+func (b *Bot) handlePage(i *discordgo.InteractionCreate) {
+	state, err := parsePageButtonState(i.MessageComponentData().CustomID)
+	b.pageResponse(i, state, err)
+}
+
+// handlePageJump opens the modal asking which page to jump to.
+func (b *Bot) handlePageJump(i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+	state, err := parsePageButtonState(customID)
+	inv, cancel := newInvocation(b.ctx, newResponder(b.session, i.Interaction, discordgo.InteractionResponseModal))
+	defer cancel()
+	var pages *storedPages
+	if err == nil {
+		pages, err = b.viewPages(inv, state.ID)
+	}
+	if err != nil {
+		b.pageResponse(i, state, err)
+		return
+	}
+
+	maxLength := len(strconv.Itoa(pages.count))
+	err = b.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			// The modal routes back with the same state as the button.
+			CustomID: customID,
+			Title:    "Jump to page",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.TextInput{
+							CustomID:    pageJumpInput,
+							Label:       fmt.Sprintf("Page (1 to %d)", pages.count),
+							Style:       discordgo.TextInputShort,
+							Required:    true,
+							MinLength:   1,
+							MaxLength:   maxLength,
+							Placeholder: strconv.Itoa(pages.count),
+						},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		slog.Error("failed to open page jump modal", "error", err)
+	}
+}
+
+// handlePageJumpSubmit shows the page entered in the jump modal.
+func (b *Bot) handlePageJumpSubmit(i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	state, err := parsePageButtonState(data.CustomID)
+	if err == nil {
+		value, _ := textInputValues(data.Components)[pageJumpInput].(string)
+		page, convErr := strconv.Atoi(strings.TrimSpace(value))
+		if convErr != nil {
+			err = UserErrorf("%q isn't a page number.", value)
+		}
+		state.Page = page - 1
+	}
+	b.pageResponse(i, state, err)
+}
@@ -0,0 +1,178 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// pageButtons returns the previous, jump and next buttons of a page.
+func pageButtons(t *testing.T, data *discordgo.InteractionResponseData) (prev, jump, next discordgo.Button) {
+	t.Helper()
+	if len(data.Components) == 0 {
+		t.Fatalf("expected navigation buttons, got none")
+	}
+	row := data.Components[0].(discordgo.ActionsRow)
+	return row.Components[0].(discordgo.Button), row.Components[1].(discordgo.Button), row.Components[2].(discordgo.Button)
+}
+
+// click builds a button click interaction.
+func click(customID string) *discordgo.Interaction {
+	return &discordgo.Interaction{
+		Type: discordgo.InteractionMessageComponent,
+		User: &discordgo.User{ID: "alice"},
+		Data: discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent},
+	}
+}
+
+func TestPaginatedCommand(t *testing.T) {
+	list := NewBotFunction("list", func(struct{}) (*Response, error) {
+		var pages []*Response
+		for n := 1; n <= 3; n++ {
+			pages = append(pages, &Response{Content: fmt.Sprintf("item %d", n)})
+		}
+		resp := Paginate(pages...)
+		resp.Ephemeral = true
+		return resp, nil
+	}, nil)
+	bot, session := newTestBot(t, []BotFunctionI{list})
+
+	record := session.Interact(commandInteraction("alice", discordgo.ApplicationCommandInteractionData{Name: "list"}))
+	data := record.Responses[0].Data
+	prev, jump, next := pageButtons(t, data)
+	if data.Content != "item 1" || data.Flags&discordgo.MessageFlagsEphemeral == 0 || !prev.Disabled || next.Disabled || jump.Label != "Page 1 of 3" {
+		t.Fatalf("unexpected first page %q with buttons %q %q %q", data.Content, prev.Label, jump.Label, next.Label)
+	}
+
+	record = session.Interact(click(next.CustomID))
+	data = record.Responses[0].Data
+	if record.Responses[0].Type != discordgo.InteractionResponseUpdateMessage || data.Content != "item 2" {
+		t.Fatalf("expected next to update the message with page 2, got %+v", record.Responses[0])
+	}
+
+	// Jumping opens a modal, whose submission shows the page entered.
+	record = session.Interact(click(jump.CustomID))
+	if record.Responses[0].Type != discordgo.InteractionResponseModal {
+		t.Fatalf("expected the jump button to open a modal, got %+v", record.Responses[0])
+	}
+	submit := func(value string) *discordgo.InteractionResponse {
+		record := session.Interact(&discordgo.Interaction{
+			Type: discordgo.InteractionModalSubmit,
+			User: &discordgo.User{ID: "alice"},
+			Data: discordgo.ModalSubmitInteractionData{
+				CustomID: record.Responses[0].Data.CustomID,
+				Components: []discordgo.MessageComponent{&discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{&discordgo.TextInput{CustomID: pageJumpInput, Value: value}},
+				}},
+			},
+		})
+		return record.Responses[0]
+	}
+	resp := submit("3")
+	_, _, next = pageButtons(t, resp.Data)
+	if resp.Type != discordgo.InteractionResponseUpdateMessage || resp.Data.Content != "item 3" || !next.Disabled {
+		t.Errorf("expected the last page, got %+v", resp.Data)
+	}
+	resp = submit("7")
+	if resp.Type != discordgo.InteractionResponseChannelMessageWithSource || resp.Data.Embeds[0].Description != "Enter a page number from 1 to 3." {
+		t.Errorf("expected an out of range page to be refused in a new message, got %+v", resp.Data)
+	}
+
+	// Other transports get every page at once.
+	merged, err := bot.Invoke(context.Background(), &Call{Command: []string{"list"}, User: &discordgo.User{ID: "alice"}})
+	if err != nil || merged.Content != "item 1\nitem 2\nitem 3" {
+		t.Errorf("unexpected merged pages %+v, %v", merged, err)
+	}
+}
+
+func TestPaginationExpiry(t *testing.T) {
+	fetched := 0
+	list := NewBotFunction("list", func(struct{}) (*Response, error) {
+		return PaginateFunc(100, func(page int) (*Response, error) {
+			fetched++
+			return &Response{Content: fmt.Sprint("page ", page+1)}, nil
+		}), nil
+	}, nil)
+	bot, session := newTestBot(t, []BotFunctionI{list}, WithPageTimeout(time.Minute))
+
+	record := session.Interact(commandInteraction("alice", discordgo.ApplicationCommandInteractionData{Name: "list"}))
+	_, _, next := pageButtons(t, record.Responses[0].Data)
+	if fetched != 1 {
+		t.Errorf("expected only the first page to be fetched, got %d", fetched)
+	}
+	for _, pages := range bot.pages.lists {
+		pages.expires = time.Now().Add(-time.Second)
+	}
+
+	record = session.Interact(click(next.CustomID))
+	data := record.Responses[0].Data
+	if data.Embeds[0].Description != "These pages have expired. Run the command again to see them." {
+		t.Errorf("expected the pages to have expired, got %+v", data)
+	}
+}
+
+func TestPagePolicy(t *testing.T) {
+	fetched := 0
+	list := NewBotFunction("list", func(struct{}) (*Response, error) {
+		return PaginateFunc(3, func(page int) (*Response, error) {
+			fetched++
+			return &Response{Content: fmt.Sprintf("item %d", page+1)}, nil
+		}), nil
+	}, nil, WithPolicy(Policy{AllowedUsers: []string{"alice"}}))
+	_, session := newTestBot(t, []BotFunctionI{list})
+
+	record := session.Interact(commandInteraction("alice", discordgo.ApplicationCommandInteractionData{Name: "list"}))
+	_, jump, next := pageButtons(t, record.Responses[0].Data)
+
+	// Others who can see the message can't page through it, or fetch pages.
+	for _, button := range []discordgo.Button{next, jump} {
+		byBob := click(button.CustomID)
+		byBob.User = &discordgo.User{ID: "bob"}
+		record = session.Interact(byBob)
+		if data := record.Responses[0].Data; len(data.Embeds) != 1 || data.Embeds[0].Description != permissionDeniedMessage {
+			t.Errorf("expected bob to be denied, got %+v", record.Responses[0])
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("expected only the first page to be fetched, got %d", fetched)
+	}
+
+	record = session.Interact(click(next.CustomID))
+	if data := record.Responses[0].Data; data.Content != "item 2" {
+		t.Errorf("expected alice to see page 2, got %+v", data)
+	}
+}
+
+func TestPaginatedNotification(t *testing.T) {
+	bot, session := newTestBot(t, nil)
+	single := Paginate(&Response{Content: "only page"})
+	err := bot.SendTo(DefaultTarget, single)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = bot.SendTo(DefaultTarget, Paginate(&Response{Content: "first"}, &Response{Content: "second"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing is sent for a nil response.
+	err = bot.SendTo(DefaultTarget, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := session.Messages()[1:]
+	if len(messages) != 3 {
+		t.Fatalf("expected every page to be sent, got %d messages", len(messages))
+	}
+	for i, want := range []string{"only page", "first", "second"} {
+		if messages[i].Content != want || len(messages[i].Components) != 0 {
+			t.Errorf("expected page %q without buttons, got %+v", want, messages[i].MessageSend)
+		}
+	}
+	if len(bot.pages.lists) != 0 {
+		t.Errorf("expected no pages to be stored for notifications, got %d", len(bot.pages.lists))
+	}
+}
@@ -0,0 +1,144 @@
+package discord
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// permissionDeniedMessage is shown to users who aren't allowed to run a command.
+const permissionDeniedMessage = "You don't have permission to use this command."
+
+// Policy restricts who can run a bot function. Every restriction that is set must be satisfied,
+// and the zero value allows everyone.
+type Policy struct {
+	// AllowedRoles and AllowedUsers, when either is set, limit the function to members with one of
+	// the roles or to one of the users.
+	AllowedRoles []string
+	AllowedUsers []string
+	// GuildOwnerOnly limits the function to the owner of the guild it is run in.
+	GuildOwnerOnly bool
+	// DMOnly limits the function to direct messages with the bot.
+	DMOnly bool
+	// Permissions requires members to hold all of these permission bits, e.g. discordgo.PermissionManageServer.
+	// Discord also uses them to hide the command from members without them.
+	Permissions int64
+}
+
+// WithPolicy restricts who can run the function. The policy is checked before the handler runs,
+// and as far as Discord supports it, also used to hide the command from users who can't run it.
+func WithPolicy(policy Policy) FunctionOption {
+	return func(cfg *FunctionConfig) {
+		cfg.Policy = policy
+	}
+}
+
+// check returns a reason the invocation is not allowed by the policy, or an empty string if it is.
+// ownerID is the owner of the guild the command was run in.
+func (p Policy) check(inv *Invocation, ownerID string) string {
+	if p.DMOnly && inv.GuildID != "" {
+		return "command is DM only"
+	}
+	if p.GuildOwnerOnly && (inv.GuildID == "" || inv.UserID() != ownerID) {
+		return "command is guild owner only"
+	}
+	if p.Permissions != 0 && inv.GuildID != "" &&
+		(inv.Member == nil || inv.Member.Permissions&p.Permissions != p.Permissions) {
+		return "missing required permissions"
+	}
+	if len(p.AllowedRoles) > 0 || len(p.AllowedUsers) > 0 {
+		allowed := slices.Contains(p.AllowedUsers, inv.UserID())
+		for _, role := range p.AllowedRoles {
+			allowed = allowed || inv.HasRole(role)
+		}
+		if !allowed {
+			return "user is not in the allowed roles or users"
+		}
+	}
+	return ""
+}
+
+// applyPolicy maps the parts of a policy Discord understands onto the command,
+// so that members who can't run it don't see it.
+func applyPolicy(cmd *discordgo.ApplicationCommand, p Policy) {
+	var permissions int64
+	switch {
+	case p.Permissions != 0:
+		permissions = p.Permissions
+	case p.GuildOwnerOnly:
+		// Owners hold every permission, so this hides the command from everyone but owners and admins.
+		permissions = discordgo.PermissionAdministrator
+	}
+	if permissions != 0 {
+		cmd.DefaultMemberPermissions = &permissions
+	}
+	if p.DMOnly {
+		dmPermission := true
+		cmd.DMPermission = &dmPermission
+	}
+}
+
+// applyConfig maps the parts of a function's config Discord understands onto its command.
+func applyConfig(cmd *discordgo.ApplicationCommand, cfg FunctionConfig) {
+	applyPolicy(cmd, cfg.Policy)
+	if cfg.DMPermission != nil {
+		cmd.DMPermission = cfg.DMPermission
+	}
+}
+
+// guarded is anything that can be given a policy: functions, context menu commands, components and modals.
+type guarded interface {
+	GetName() string
+	GetConfig() FunctionConfig
+}
+
+// denyReason checks the policy and DM permission of a function or component against the
+// invocation, returning why it isn't allowed, or an empty string if it is.
+func (b *Bot) denyReason(inv *Invocation, fn guarded) string {
+	cfg := fn.GetConfig()
+	reason := cfg.Policy.check(inv, b.guildOwner(inv.GuildID, cfg.Policy))
+	if reason == "" && inv.GuildID == "" && cfg.DMPermission != nil && !*cfg.DMPermission {
+		reason = "command is not allowed in DMs"
+	}
+	return reason
+}
+
+// authorize checks every function in the command chain against the invocation.
+// It reports whether all of them allow it, logging why if not.
+func (b *Bot) authorize(inv *Invocation, chain []BotFunctionI) bool {
+	for _, fn := range chain {
+		if !b.allowed(inv, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowed checks a single function or component against the invocation, logging why if it
+// isn't allowed.
+func (b *Bot) allowed(inv *Invocation, fn guarded) bool {
+	reason := b.denyReason(inv, fn)
+	if reason == "" {
+		return true
+	}
+	slog.Warn("command denied",
+		"command", fn.GetName(),
+		"user_id", inv.UserID(),
+		"guild", inv.GuildID,
+		"reason", reason)
+	return false
+}
+
+// guildOwner returns the owner of the guild if the policy needs it.
+func (b *Bot) guildOwner(guildID string, policy Policy) string {
+	if !policy.GuildOwnerOnly || guildID == "" || b.session == nil {
+		return ""
+	}
+	guild, err := b.session.Guild(guildID)
+	if err != nil {
+		slog.Error("failed to look up guild owner", "guild", guildID, "error", err)
+		return ""
+	}
+	return guild.OwnerID
+}
@@ -0,0 +1,66 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	member := &Invocation{
+		GuildID: "guild",
+		User:    &discordgo.User{ID: "alice"},
+		Member:  &discordgo.Member{Roles: []string{"finance"}, Permissions: discordgo.PermissionSendMessages},
+	}
+	dm := &Invocation{User: &discordgo.User{ID: "bob"}}
+
+	tests := []struct {
+		name    string
+		policy  Policy
+		inv     *Invocation
+		ownerID string
+		allowed bool
+	}{
+		{"zero policy", Policy{}, member, "", true},
+		{"allowed role", Policy{AllowedRoles: []string{"finance"}}, member, "", true},
+		{"allowed user", Policy{AllowedRoles: []string{"admin"}, AllowedUsers: []string{"alice"}}, member, "", true},
+		{"not allowed", Policy{AllowedRoles: []string{"admin"}}, member, "", false},
+		{"owner", Policy{GuildOwnerOnly: true}, member, "alice", true},
+		{"not owner", Policy{GuildOwnerOnly: true}, member, "carol", false},
+		{"dm only in guild", Policy{DMOnly: true}, member, "", false},
+		{"dm only in dm", Policy{DMOnly: true}, dm, "", true},
+		{"has permissions", Policy{Permissions: discordgo.PermissionSendMessages}, member, "", true},
+		{"missing permissions", Policy{Permissions: discordgo.PermissionManageServer}, member, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := tt.policy.check(tt.inv, tt.ownerID)
+			if (reason == "") != tt.allowed {
+				t.Fatalf("expected allowed=%v, got reason %q", tt.allowed, reason)
+			}
+		})
+	}
+}
+
+func TestAuthorizeDMPermission(t *testing.T) {
+	b := &Bot{}
+	fn := NewBotFunction("guild_only", func(struct{}) (*Response, error) { return nil, nil }, nil,
+		WithDMPermission(false))
+
+	dm := &Invocation{User: &discordgo.User{ID: "bob"}}
+	if b.authorize(dm, []BotFunctionI{fn}) {
+		t.Error("expected command to be denied in DMs")
+	}
+	guild := &Invocation{GuildID: "guild", User: &discordgo.User{ID: "bob"}}
+	if !b.authorize(guild, []BotFunctionI{fn}) {
+		t.Error("expected command to be allowed in guilds")
+	}
+
+	cmd, err := fn.GetCommand()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.DMPermission == nil || *cmd.DMPermission {
+		t.Error("expected DM permission to be registered as false")
+	}
+}
@@ -0,0 +1,191 @@
+package discord
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// buildCommands generates the application command for every registered function and context menu command.
+func (b *Bot) buildCommands() ([]*discordgo.ApplicationCommand, error) {
+	functions := slices.Clone(b.functions)
+	for _, m := range b.contextMenus {
+		functions = append(functions, m)
+	}
+
+	var commands []*discordgo.ApplicationCommand
+	for _, fn := range functions {
+		cmd, err := fn.GetCommand()
+		if err != nil {
+			slog.Error("failed to generate command", "command", fn.GetName(), "error", err)
+			return nil, err
+		}
+		slog.Debug("initialising function", "name", fn.GetName(), "options", cmd.Options)
+		commands = append(commands, cmd)
+	}
+	return commands, nil
+}
+
+// syncCommands makes the commands registered in a guild, or globally when guildID is empty, match
+// desired. Unless BulkOverwrite is set, it compares against what Discord reports and only creates,
+// edits or deletes the commands that differ, so unchanged commands never disappear for users.
+func (b *Bot) syncCommands(guildID string, desired []*discordgo.ApplicationCommand) error {
+	appID := b.config.AppID
+
+	if b.config.BulkOverwrite {
+		// Discord treats an empty list as "delete everything", but rejects null.
+		if desired == nil {
+			desired = []*discordgo.ApplicationCommand{}
+		}
+		_, err := b.session.ApplicationCommandBulkOverwrite(appID, guildID, desired)
+		if err != nil {
+			return fmt.Errorf("failed to overwrite commands: %w", err)
+		}
+		slog.Debug("overwrote commands", "guild", guildID, "commands", len(desired))
+		return nil
+	}
+
+	existingCommands, err := b.session.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return fmt.Errorf("failed to get commands: %w", err)
+	}
+
+	existing := make(map[string]*discordgo.ApplicationCommand, len(existingCommands))
+	for _, cmd := range existingCommands {
+		existing[commandKey(cmd)] = cmd
+	}
+
+	for _, cmd := range desired {
+		key := commandKey(cmd)
+		current, ok := existing[key]
+		delete(existing, key)
+
+		switch {
+		case !ok:
+			_, err = b.session.ApplicationCommandCreate(appID, guildID, cmd)
+			if err != nil {
+				return fmt.Errorf("failed to create command %s: %w", cmd.Name, err)
+			}
+			slog.Info("created command", "guild", guildID, "command", cmd.Name)
+		case !commandsEqual(current, cmd):
+			_, err = b.session.ApplicationCommandEdit(appID, guildID, current.ID, cmd)
+			if err != nil {
+				return fmt.Errorf("failed to edit command %s: %w", cmd.Name, err)
+			}
+			slog.Info("updated command", "guild", guildID, "command", cmd.Name)
+		default:
+			slog.Debug("command unchanged", "guild", guildID, "command", cmd.Name)
+		}
+	}
+
+	// Anything left over is no longer provided by the bot.
+	for _, cmd := range existing {
+		err = b.session.ApplicationCommandDelete(appID, guildID, cmd.ID)
+		if err != nil {
+			slog.Error("failed to delete command", "guild", guildID, "command", cmd.Name, "error", err)
+			continue
+		}
+		slog.Info("deleted command", "guild", guildID, "command", cmd.Name)
+	}
+
+	return nil
+}
+
+// commandKey identifies a command by type and name, since a slash command and a context menu
+// command may share a name.
+func commandKey(cmd *discordgo.ApplicationCommand) string {
+	return fmt.Sprintf("%d:%s", commandType(cmd), cmd.Name)
+}
+
+// commandType returns the command's type, treating unset as a slash command as Discord does.
+func commandType(cmd *discordgo.ApplicationCommand) discordgo.ApplicationCommandType {
+	if cmd.Type == 0 {
+		return discordgo.ChatApplicationCommand
+	}
+	return cmd.Type
+}
+
+// commandsEqual reports whether two commands would look and behave the same to users.
+// Fields Discord fills in with defaults are normalised before comparing.
+func commandsEqual(a, b *discordgo.ApplicationCommand) bool {
+	return commandType(a) == commandType(b) &&
+		a.Name == b.Name &&
+		a.Description == b.Description &&
+		int64PtrEqual(a.DefaultMemberPermissions, b.DefaultMemberPermissions) &&
+		boolPtrValue(a.DMPermission, true) == boolPtrValue(b.DMPermission, true) &&
+		boolPtrValue(a.NSFW, false) == boolPtrValue(b.NSFW, false) &&
+		optionsEqual(a.Options, b.Options)
+}
+
+// optionsEqual reports whether two lists of command options are equivalent, including nested options.
+func optionsEqual(a, b []*discordgo.ApplicationCommandOption) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		x, y := a[i], b[i]
+		if x.Type != y.Type ||
+			x.Name != y.Name ||
+			x.Description != y.Description ||
+			x.Required != y.Required ||
+			x.Autocomplete != y.Autocomplete ||
+			x.MaxValue != y.MaxValue ||
+			x.MaxLength != y.MaxLength ||
+			!float64PtrEqual(x.MinValue, y.MinValue) ||
+			intPtrValue(x.MinLength) != intPtrValue(y.MinLength) ||
+			!slices.Equal(x.ChannelTypes, y.ChannelTypes) ||
+			!choicesEqual(x.Choices, y.Choices) ||
+			!optionsEqual(x.Options, y.Options) {
+			return false
+		}
+	}
+	return true
+}
+
+// choicesEqual reports whether two lists of choices match. Values are compared by their text,
+// since numbers come back from Discord as float64 whatever type they were registered with.
+func choicesEqual(a, b []*discordgo.ApplicationCommandOptionChoice) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || fmt.Sprint(a[i].Value) != fmt.Sprint(b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// int64PtrEqual reports whether two optional int64 values are both unset or both set to the same value.
+func int64PtrEqual(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// float64PtrEqual reports whether two optional float64 values are both unset or both set to the same value.
+func float64PtrEqual(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// boolPtrValue returns the value of an optional bool, or def if it is unset.
+func boolPtrValue(v *bool, def bool) bool {
+	if v == nil {
+		return def
+	}
+	return *v
+}
+
+// intPtrValue returns the value of an optional int, or zero if it is unset.
+func intPtrValue(v *int) int {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
@@ -0,0 +1,48 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCommandsEqual(t *testing.T) {
+	desired := &discordgo.ApplicationCommand{
+		Name:        "retreive_zaps",
+		Description: "Auto-generated command for retreive_zaps",
+		Options: []*discordgo.ApplicationCommandOption{{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "years",
+			Description: "Number of years",
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "One", Value: "1"},
+			},
+		}},
+	}
+
+	// Discord fills in the type, DM permission and ID, and returns choice values as numbers.
+	dmPermission := true
+	registered := &discordgo.ApplicationCommand{
+		ID:           "123",
+		Type:         discordgo.ChatApplicationCommand,
+		Name:         "retreive_zaps",
+		Description:  "Auto-generated command for retreive_zaps",
+		DMPermission: &dmPermission,
+		Options: []*discordgo.ApplicationCommandOption{{
+			Type:        discordgo.ApplicationCommandOptionInteger,
+			Name:        "years",
+			Description: "Number of years",
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{Name: "One", Value: float64(1)},
+			},
+		}},
+	}
+	if !commandsEqual(registered, desired) {
+		t.Fatal("expected registered command to match desired command")
+	}
+
+	registered.Options[0].Required = true
+	if commandsEqual(registered, desired) {
+		t.Fatal("expected a changed option to be detected")
+	}
+}
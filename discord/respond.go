@@ -0,0 +1,256 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// tokenLifetime is how long an interaction token stays valid for editing the response and sending followups.
+const tokenLifetime = 15 * time.Minute
+
+// autoDeferAfter is how long a handler may run before the bot defers the response,
+// leaving headroom before Discord's three second deadline.
+const autoDeferAfter = 2 * time.Second
+
+// errorColor is the embed color used for errors.
+const errorColor = 0xFF0000
+
+// responder tracks the response to a single interaction. Discord requires an initial response within
+// three seconds; if the handler takes longer, the responder sends a deferred response instead and
+// later edits it with the handler's result.
+type responder struct {
+	session     Session
+	interaction *discordgo.Interaction
+	// responseType is the type of the initial response when it isn't deferred.
+	responseType discordgo.InteractionResponseType
+	// deferType is the type of the deferred response.
+	deferType discordgo.InteractionResponseType
+
+	mu        sync.Mutex
+	timer     *time.Timer
+	deferred  bool
+	responded bool
+	// ephemeral makes new messages visible only to the invoking user.
+	ephemeral bool
+	// deferredEphemeral records whether the deferred response was ephemeral, as Discord fixes
+	// the visibility of a message when it is first sent.
+	deferredEphemeral bool
+}
+
+// newResponder creates a responder that answers with responseType, deferring with the matching
+// deferred type: updates to a component's message defer as a message update, everything else
+// defers as a new message.
+func newResponder(s Session, i *discordgo.Interaction, responseType discordgo.InteractionResponseType) *responder {
+	deferType := discordgo.InteractionResponseDeferredChannelMessageWithSource
+	if responseType == discordgo.InteractionResponseUpdateMessage {
+		deferType = discordgo.InteractionResponseDeferredMessageUpdate
+	}
+	return &responder{
+		session:      s,
+		interaction:  i,
+		responseType: responseType,
+		deferType:    deferType,
+	}
+}
+
+// autoDefer defers the response if no response has been sent by the time autoDeferAfter elapses.
+func (r *responder) autoDefer() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timer = time.AfterFunc(autoDeferAfter, func() {
+		if err := r.deferResponse(); err != nil {
+			slog.Error("failed to defer interaction response", "interaction", r.interaction.ID, "error", err)
+		}
+	})
+}
+
+// setEphemeral sets whether new messages sent in response are visible only to the invoking user.
+func (r *responder) setEphemeral(ephemeral bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ephemeral = ephemeral
+}
+
+// isEphemeral reports whether resp would be sent as an ephemeral message.
+func (r *responder) isEphemeral(resp *Response) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.ephemeral || (resp != nil && resp.Ephemeral)
+}
+
+// deferResponse acknowledges the interaction so the handler can take up to the token lifetime to finish.
+// It does nothing if the response has already been deferred or sent.
+func (r *responder) deferResponse() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.deferred || r.responded {
+		return nil
+	}
+
+	resp := &discordgo.InteractionResponse{
+		Type: r.deferType,
+	}
+	if r.ephemeral && r.deferType == discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		resp.Data = &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral}
+	}
+	err := r.session.InteractionRespond(r.interaction, resp)
+	if err != nil {
+		return fmt.Errorf("failed to defer response: %w", err)
+	}
+	r.deferred = true
+	r.deferredEphemeral = resp.Data != nil
+	slog.Debug("deferred interaction response", "interaction", r.interaction.ID)
+	return nil
+}
+
+// respond sends the handler's result, either as the initial response or, if the response was
+// deferred, by editing it. If the deferred message's visibility doesn't match the result's, it is
+// replaced by a followup instead, since Discord can't change the visibility of a message.
+// opensModal reports whether data describes a modal, which can only be sent as the initial response.
+func (r *responder) respond(data *discordgo.InteractionResponseData, opensModal bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	if r.responded {
+		return errors.New("interaction has already been responded to")
+	}
+	r.responded = true
+
+	responseType := r.responseType
+	if opensModal {
+		responseType = discordgo.InteractionResponseModal
+	}
+	newMessage := responseType == discordgo.InteractionResponseChannelMessageWithSource
+	if r.deferred && data == nil {
+		// A deferred response is edited or followed up, which needs data even when there's nothing to show.
+		data = &discordgo.InteractionResponseData{}
+	}
+	if r.ephemeral && newMessage && data != nil {
+		data.Flags |= discordgo.MessageFlagsEphemeral
+	}
+
+	if !r.deferred {
+		return r.session.InteractionRespond(r.interaction, &discordgo.InteractionResponse{
+			Type: responseType,
+			Data: data,
+		})
+	}
+
+	if opensModal {
+		return errors.New("a modal can't be opened after the response has been deferred")
+	}
+	ephemeral := data != nil && data.Flags&discordgo.MessageFlagsEphemeral != 0
+	if newMessage && ephemeral != r.deferredEphemeral {
+		err := r.session.InteractionResponseDelete(r.interaction)
+		if err != nil {
+			return fmt.Errorf("failed to delete deferred response: %w", err)
+		}
+		_, err = r.session.FollowupMessageCreate(r.interaction, true, webhookParams(data))
+		return err
+	}
+	_, err := r.session.InteractionResponseEdit(r.interaction, webhookEdit(data))
+	return err
+}
+
+// respondError reports a failure without replacing the message a component is attached to:
+// errors are sent as a new message, or as a followup once a message update has been deferred.
+func (r *responder) respondError(data *discordgo.InteractionResponseData) error {
+	r.mu.Lock()
+	if r.timer != nil {
+		r.timer.Stop()
+	}
+	deferredUpdate := r.deferred && r.deferType == discordgo.InteractionResponseDeferredMessageUpdate
+	if deferredUpdate || r.responded {
+		r.responded = true
+		r.mu.Unlock()
+		_, err := r.session.FollowupMessageCreate(r.interaction, true, webhookParams(data))
+		return err
+	}
+	r.responseType = discordgo.InteractionResponseChannelMessageWithSource
+	r.mu.Unlock()
+	return r.respond(data, false)
+}
+
+// progress shows an interim status message while the handler is still running,
+// deferring the response first if necessary.
+func (r *responder) progress(content string) error {
+	if err := r.deferResponse(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Once the result is in, progress updates would overwrite it.
+	if r.responded || r.deferType != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		return nil
+	}
+	_, err := r.session.InteractionResponseEdit(r.interaction, &discordgo.WebhookEdit{
+		Content: &content,
+	})
+	return err
+}
+
+// followup sends an additional message, deferring the response first if nothing has been sent
+// yet, since Discord only accepts followups after the initial response. A nil response sends nothing.
+func (r *responder) followup(resp *Response) error {
+	if resp == nil {
+		return nil
+	}
+	if err := r.deferResponse(); err != nil {
+		return err
+	}
+	data := resp.interactionData()
+	if r.isEphemeral(resp) {
+		data.Flags |= discordgo.MessageFlagsEphemeral
+	}
+	_, err := r.session.FollowupMessageCreate(r.interaction, true, webhookParams(data))
+	return err
+}
+
+// webhookParams converts response data into a followup message.
+func webhookParams(data *discordgo.InteractionResponseData) *discordgo.WebhookParams {
+	return &discordgo.WebhookParams{
+		Content:         data.Content,
+		Embeds:          data.Embeds,
+		Components:      data.Components,
+		Files:           data.Files,
+		Flags:           data.Flags,
+		AllowedMentions: data.AllowedMentions,
+	}
+}
+
+// webhookEdit converts response data into an edit of the original response. Every field is set
+// so that anything shown while the response was deferred, such as progress text, is replaced.
+func webhookEdit(data *discordgo.InteractionResponseData) *discordgo.WebhookEdit {
+	content := data.Content
+	// Empty rather than nil slices, which would be sent as null.
+	components := append([]discordgo.MessageComponent{}, data.Components...)
+	embeds := append([]*discordgo.MessageEmbed{}, data.Embeds...)
+	return &discordgo.WebhookEdit{
+		Content:         &content,
+		Components:      &components,
+		Embeds:          &embeds,
+		Files:           data.Files,
+		AllowedMentions: data.AllowedMentions,
+	}
+}
+
+// errorResponse builds the response data for a failed interaction. Errors are only shown
+// to the user who ran into them.
+func errorResponse(description string) *discordgo.InteractionResponseData {
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{{
+			Title:       "Error",
+			Description: description,
+			Color:       errorColor,
+		}},
+		Flags: discordgo.MessageFlagsEphemeral,
+	}
+}
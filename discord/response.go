@@ -0,0 +1,82 @@
+package discord
+
+import (
+	"bytes"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// File is a file attached to a response, such as a CSV export or a chart. The contents are held
+// in memory so the same response can be sent to several channels.
+type File struct {
+	// Name is the file name shown in Discord, e.g. "report.csv".
+	Name string
+	// ContentType is the MIME type, e.g. "text/csv". Discord guesses it from the name if empty.
+	ContentType string
+	Data        []byte
+}
+
+// Response is what command, component and modal handlers and schedules return. It can carry
+// content, several embeds, file attachments and components. For interactions it can also be
+// ephemeral; schedules ignore that, as their messages are never a reply to a single user.
+type Response struct {
+	Content    string
+	Embeds     []*discordgo.MessageEmbed
+	Files      []*File
+	Components []discordgo.MessageComponent
+	// Ephemeral makes an interaction response visible only to the user who triggered it.
+	Ephemeral bool
+
+	// modal is set instead of the other fields when the response opens a modal.
+	modal *discordgo.InteractionResponseData
+	// pages is set instead of the content when the response is paginated.
+	pages *pageList
+}
+
+// discordFiles returns the attachments as discordgo files, each with its own reader.
+func (r *Response) discordFiles() []*discordgo.File {
+	var files []*discordgo.File
+	for _, f := range r.Files {
+		files = append(files, &discordgo.File{
+			Name:        f.Name,
+			ContentType: f.ContentType,
+			Reader:      bytes.NewReader(f.Data),
+		})
+	}
+	return files
+}
+
+// opensModal reports whether the response opens a modal rather than sending a message.
+func (r *Response) opensModal() bool {
+	return r != nil && r.modal != nil
+}
+
+// interactionData converts the response into interaction response data. A nil response converts to nil.
+func (r *Response) interactionData() *discordgo.InteractionResponseData {
+	if r == nil {
+		return nil
+	}
+	if r.modal != nil {
+		return r.modal
+	}
+	data := &discordgo.InteractionResponseData{
+		Content:    r.Content,
+		Embeds:     r.Embeds,
+		Components: r.Components,
+		Files:      r.discordFiles(),
+	}
+	if r.Ephemeral {
+		data.Flags = discordgo.MessageFlagsEphemeral
+	}
+	return data
+}
+
+// messageSend converts the response into a channel message.
+func (r *Response) messageSend() *discordgo.MessageSend {
+	return &discordgo.MessageSend{
+		Content:    r.Content,
+		Embeds:     r.Embeds,
+		Components: r.Components,
+		Files:      r.discordFiles(),
+	}
+}
@@ -0,0 +1,37 @@
+package discord
+
+import (
+	"io"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestResponseConversion(t *testing.T) {
+	resp := &Response{
+		Content:   "report",
+		Files:     []*File{{Name: "report.csv", ContentType: "text/csv", Data: []byte("a,b\n")}},
+		Ephemeral: true,
+	}
+
+	data := resp.interactionData()
+	if data.Flags != discordgo.MessageFlagsEphemeral || data.Content != "report" {
+		t.Errorf("unexpected interaction data %+v", data)
+	}
+
+	// Every conversion reads the file from the start, so a response can be sent more than once.
+	for range 2 {
+		msg := resp.messageSend()
+		if len(msg.Files) != 1 || msg.Files[0].Name != "report.csv" {
+			t.Fatalf("unexpected files %+v", msg.Files)
+		}
+		body, err := io.ReadAll(msg.Files[0].Reader)
+		if err != nil || string(body) != "a,b\n" {
+			t.Errorf("unexpected file contents %q, %v", body, err)
+		}
+	}
+
+	if (*Response)(nil).interactionData() != nil {
+		t.Error("expected a nil response to convert to nil")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/bwmarrin/discordgo"
 	"github.com/robfig/cron/v3"
 )
 
@@ -15,8 +14,30 @@ type BotScheduleI interface {
 	GetName() string
 	// GetCronExpression returns the cron expression for when this schedule should run
 	GetCronExpression() string
-	// Execute runs the scheduled task and returns an embed to send (or nil if no notification needed)
-	Execute() (*discordgo.MessageEmbed, error)
+	// GetTarget returns the name of the notification target the schedule's responses are sent to
+	GetTarget() string
+	// NotifiesFailures reports whether failures are announced to the target, showing only an error ID
+	NotifiesFailures() bool
+	// Execute runs the scheduled task and returns a response to send (or nil if no notification needed)
+	Execute() (*Response, error)
+}
+
+// ScheduleOption is a function that configures optional settings of a GenericBotSchedule.
+type ScheduleOption func(*GenericBotSchedule)
+
+// WithTarget sends the schedule's notifications to the named target instead of DefaultTarget.
+func WithTarget(target string) ScheduleOption {
+	return func(bs *GenericBotSchedule) {
+		bs.Target = target
+	}
+}
+
+// WithFailureNotice announces the schedule's failures to its target. The notice only names the
+// schedule and the error ID the failure is logged against, so no internal details are posted.
+func WithFailureNotice() ScheduleOption {
+	return func(bs *GenericBotSchedule) {
+		bs.NotifyFailures = true
+	}
 }
 
 // GenericBotSchedule is a generic implementation of BotScheduleI
@@ -26,7 +47,11 @@ type GenericBotSchedule struct {
 	// CronExpression determines when the schedule will execute
 	CronExpression string
 	// Handler is the function to execute on schedule
-	Handler func() (*discordgo.MessageEmbed, error)
+	Handler func() (*Response, error)
+	// Target is the name of the notification target to send responses to
+	Target string
+	// NotifyFailures announces failures to the target, with only their error ID
+	NotifyFailures bool
 }
 
 // GetName returns the schedule's name
@@ -39,18 +64,34 @@ func (bs *GenericBotSchedule) GetCronExpression() string {
 	return bs.CronExpression
 }
 
+// GetTarget returns the schedule's notification target
+func (bs *GenericBotSchedule) GetTarget() string {
+	return bs.Target
+}
+
+// NotifiesFailures reports whether the schedule's failures are announced to its target
+func (bs *GenericBotSchedule) NotifiesFailures() bool {
+	return bs.NotifyFailures
+}
+
 // Execute runs the scheduled task
-func (bs *GenericBotSchedule) Execute() (*discordgo.MessageEmbed, error) {
+func (bs *GenericBotSchedule) Execute() (*Response, error) {
 	return bs.Handler()
 }
 
-// NewBotSchedule creates a new scheduled task with the given name, cron expression, and handler
-func NewBotSchedule(name string, cronExpr string, handler func() (*discordgo.MessageEmbed, error)) BotScheduleI {
-	return &GenericBotSchedule{
+// NewBotSchedule creates a new scheduled task with the given name, cron expression, and handler.
+// Its notifications go to DefaultTarget unless opts name another target.
+func NewBotSchedule(name string, cronExpr string, handler func() (*Response, error), opts ...ScheduleOption) BotScheduleI {
+	bs := &GenericBotSchedule{
 		Name:           name,
 		CronExpression: cronExpr,
 		Handler:        handler,
+		Target:         DefaultTarget,
+	}
+	for _, opt := range opts {
+		opt(bs)
 	}
+	return bs
 }
 
 // scheduleManager handles scheduling and executing tasks
@@ -97,47 +138,29 @@ func (sm *scheduleManager) start() error {
 func (sm *scheduleManager) executeSchedule(schedule BotScheduleI) {
 	slog.Debug("executing schedule", "name", schedule.GetName(), "cron", schedule.GetCronExpression())
 
-	embed, err := schedule.Execute()
-	if err != nil {
-		slog.Error("failed to execute schedule",
-			"name", schedule.GetName(),
-			"error", err)
+	resp, err := recovered(schedule.Execute)
+	if err != nil && !schedule.NotifiesFailures() {
+		LogError("failed to execute schedule", err, "name", schedule.GetName())
 		return
 	}
+	if err != nil {
+		// The notice is built like an error shown to a user, so internal failures only show their ID.
+		notice := reportError("failed to execute schedule", err, "name", schedule.GetName())
+		notice.Embeds[0].Title = fmt.Sprintf("Schedule %s failed", schedule.GetName())
+		resp = &Response{Embeds: notice.Embeds}
+	}
 
-	// If the embed is nil, no notification is needed
-	if embed == nil {
+	// If the response is nil, no notification is needed
+	if resp == nil {
 		return
 	}
 
-	// Send the embed to all guilds
-	for _, guild := range sm.bot.session.State.Guilds {
-		// Find the first text channel to send the notification
-		channels, err := sm.bot.session.GuildChannels(guild.ID)
-		if err != nil {
-			slog.Error("failed to get guild channels",
-				"guild", guild.ID,
-				"error", err)
-			continue
-		}
-
-		var targetChannel string
-		for _, channel := range channels {
-			if channel.Type == discordgo.ChannelTypeGuildText {
-				targetChannel = channel.ID
-				break
-			}
-		}
-
-		if targetChannel != "" {
-			_, err = sm.bot.session.ChannelMessageSendEmbed(targetChannel, embed)
-			if err != nil {
-				slog.Error("failed to send schedule notification",
-					"guild", guild.ID,
-					"schedule", schedule.GetName(),
-					"error", err)
-			}
-		}
+	err = sm.bot.SendTo(schedule.GetTarget(), resp)
+	if err != nil {
+		slog.Error("failed to send schedule notification",
+			"schedule", schedule.GetName(),
+			"target", schedule.GetTarget(),
+			"error", err)
 	}
 }
 
@@ -0,0 +1,88 @@
+package discord
+
+import (
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Session is the part of the Discord API the bot uses. NewSession provides one backed by a
+// discordgo session; the discordtest package provides an in-memory fake for tests.
+//
+// Event handlers are added with AddHandler using discordgo's handler signatures, such as
+// func(*discordgo.Session, *discordgo.InteractionCreate). The bot ignores the session passed to
+// them and uses the Session it was created with, so fakes may pass nil.
+type Session interface {
+	Open() error
+	Close() error
+	AddHandler(handler interface{}) func()
+
+	// BotUserID returns the ID of the bot's own user, which is known once the session is open.
+	BotUserID() string
+	// Guilds returns the guilds the bot is in.
+	Guilds() []*discordgo.Guild
+	Guild(guildID string, options ...discordgo.RequestOption) (*discordgo.Guild, error)
+	GuildChannels(guildID string, options ...discordgo.RequestOption) ([]*discordgo.Channel, error)
+	UserChannelCreate(recipientID string, options ...discordgo.RequestOption) (*discordgo.Channel, error)
+
+	ApplicationCommands(appID, guildID string, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+	ApplicationCommandCreate(appID, guildID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandEdit(appID, guildID, cmdID string, cmd *discordgo.ApplicationCommand, options ...discordgo.RequestOption) (*discordgo.ApplicationCommand, error)
+	ApplicationCommandDelete(appID, guildID, cmdID string, options ...discordgo.RequestOption) error
+	ApplicationCommandBulkOverwrite(appID, guildID string, commands []*discordgo.ApplicationCommand, options ...discordgo.RequestOption) ([]*discordgo.ApplicationCommand, error)
+
+	ChannelMessageSend(channelID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendEmbed(channelID string, embed *discordgo.MessageEmbed, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendReply(channelID, content string, reference *discordgo.MessageReference, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendEmbedReply(channelID string, embed *discordgo.MessageEmbed, reference *discordgo.MessageReference, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	MessageReactionAdd(channelID, messageID, emojiID string, options ...discordgo.RequestOption) error
+
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
+	InteractionResponseEdit(interaction *discordgo.Interaction, newresp *discordgo.WebhookEdit, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	InteractionResponseDelete(interaction *discordgo.Interaction, options ...discordgo.RequestOption) error
+	FollowupMessageCreate(interaction *discordgo.Interaction, wait bool, data *discordgo.WebhookParams, options ...discordgo.RequestOption) (*discordgo.Message, error)
+}
+
+// discordSession adapts a discordgo session to Session, reading the bot's user and guilds from
+// the session's state cache.
+type discordSession struct {
+	*discordgo.Session
+}
+
+// NewSession creates a Session that connects to Discord with the bot token, asking for the intents
+// the bot needs to see guilds, messages and DMs.
+func NewSession(botToken string) (Session, error) {
+	dg, err := discordgo.New("Bot " + botToken)
+	if err != nil {
+		return nil, err
+	}
+	dg.Identify.Intents = discordgo.IntentsGuilds | discordgo.IntentsGuildMessages | discordgo.IntentsDirectMessages |
+		discordgo.IntentsMessageContent
+	return discordSession{dg}, nil
+}
+
+// BotUserID returns the ID of the user the session is logged in as.
+func (s discordSession) BotUserID() string {
+	s.State.RLock()
+	defer s.State.RUnlock()
+	if s.State.User == nil {
+		return ""
+	}
+	return s.State.User.ID
+}
+
+// Guilds returns the guilds in the state cache.
+func (s discordSession) Guilds() []*discordgo.Guild {
+	s.State.RLock()
+	defer s.State.RUnlock()
+	return slices.Clone(s.State.Guilds)
+}
+
+// Guild returns the guild from the state cache, fetching it if it isn't cached.
+func (s discordSession) Guild(guildID string, options ...discordgo.RequestOption) (*discordgo.Guild, error) {
+	if guild, err := s.State.Guild(guildID); err == nil {
+		return guild, nil
+	}
+	return s.Session.Guild(guildID, options...)
+}
@@ -0,0 +1,104 @@
+package discord
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// shareComponent is the custom ID of the button that reposts an ephemeral response publicly.
+const shareComponent = "share"
+
+// maxActionRows is the most rows of components Discord allows on a message.
+const maxActionRows = 5
+
+// addShareButton adds a row with a "Share" button to the response, if there is room for one.
+// Responses that open a modal are left alone.
+func addShareButton(resp *Response) {
+	if resp == nil || resp.modal != nil || len(resp.Components) >= maxActionRows {
+		return
+	}
+	resp.Components = append(resp.Components, discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "Share",
+				Style:    discordgo.SecondaryButton,
+				CustomID: shareComponent,
+			},
+		},
+	})
+}
+
+// hasShareButton reports whether a message carries the share button, so that responses
+// replacing it can keep the button.
+func hasShareButton(msg *discordgo.Message) bool {
+	if msg == nil {
+		return false
+	}
+	for _, component := range msg.Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range row.Components {
+			if button, ok := c.(*discordgo.Button); ok && button.CustomID == shareComponent {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sharedComponents returns the components to repost with a shared response: all but the share
+// button and the page navigation buttons, which would show the sharer's other pages to the whole
+// channel, along with any rows left empty by removing them.
+func sharedComponents(components []discordgo.MessageComponent) []discordgo.MessageComponent {
+	var kept []discordgo.MessageComponent
+	for _, component := range components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			kept = append(kept, component)
+			continue
+		}
+		var buttons []discordgo.MessageComponent
+		for _, c := range row.Components {
+			if button, ok := c.(*discordgo.Button); ok {
+				name, _, _ := strings.Cut(button.CustomID, customIDSeparator)
+				if name == shareComponent || name == pageComponent || name == pageJumpComponent {
+					continue
+				}
+			}
+			buttons = append(buttons, c)
+		}
+		if len(buttons) > 0 {
+			kept = append(kept, &discordgo.ActionsRow{Components: buttons})
+		}
+	}
+	return kept
+}
+
+// handleShare reposts the ephemeral message the share button is attached to as a public message.
+// Attachments aren't carried over, and the components that remain are still subject to their own
+// policies when others use them.
+func (b *Bot) handleShare(i *discordgo.InteractionCreate) {
+	msg := i.Message
+	if msg == nil {
+		slog.Warn("share clicked without a message", "interaction", i.ID)
+		return
+	}
+
+	err := b.session.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg.Content,
+			Embeds:     msg.Embeds,
+			Components: sharedComponents(msg.Components),
+			// Don't ping anyone mentioned in the original response a second time.
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+	if err != nil {
+		slog.Error("failed to share response", "interaction", i.ID, "error", err)
+	}
+}
@@ -0,0 +1,68 @@
+package discord
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestShareButton(t *testing.T) {
+	data := &Response{Content: "result"}
+	addShareButton(data)
+	if len(data.Components) != 1 {
+		t.Fatalf("expected a share row, got %d rows", len(data.Components))
+	}
+
+	// Components come back from Discord as pointers.
+	received := []discordgo.MessageComponent{
+		&discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			&discordgo.Button{CustomID: "zaps_refresh"},
+			&discordgo.Button{CustomID: shareComponent},
+		}},
+		&discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			&discordgo.Button{CustomID: pageComponent + "?id=abc&page=1"},
+			&discordgo.Button{CustomID: pageJumpComponent + "?id=abc"},
+		}},
+		&discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			&discordgo.Button{CustomID: shareComponent},
+		}},
+	}
+	kept := sharedComponents(received)
+	if len(kept) != 1 {
+		t.Fatalf("expected the empty rows to be dropped, got %d rows", len(kept))
+	}
+	row := kept[0].(*discordgo.ActionsRow)
+	if len(row.Components) != 1 || row.Components[0].(*discordgo.Button).CustomID != "zaps_refresh" {
+		t.Errorf("unexpected components %+v", row.Components)
+	}
+
+	full := &Response{Components: make([]discordgo.MessageComponent, maxActionRows)}
+	addShareButton(full)
+	if len(full.Components) != maxActionRows {
+		t.Error("share button should not be added when there is no room")
+	}
+}
+
+func TestShareButtonKeptOnUpdate(t *testing.T) {
+	refresh := NewBotComponent("refresh", func(state struct{}, values []string) (*Response, error) {
+		return &Response{Content: "fresh"}, nil
+	})
+	_, session := newTestBot(t, nil, WithComponents(refresh))
+
+	clickOn := func(msg *discordgo.Message) *discordgo.InteractionResponseData {
+		i := click("refresh")
+		i.Message = msg
+		return session.Interact(i).Responses[0].Data
+	}
+
+	shareable := &discordgo.Message{Flags: discordgo.MessageFlagsEphemeral, Components: []discordgo.MessageComponent{
+		&discordgo.ActionsRow{Components: []discordgo.MessageComponent{&discordgo.Button{CustomID: shareComponent}}},
+	}}
+	data := clickOn(shareable)
+	if len(data.Components) != 1 || data.Components[0].(discordgo.ActionsRow).Components[0].(discordgo.Button).CustomID != shareComponent {
+		t.Errorf("expected the refreshed message to keep its share button, got %+v", data.Components)
+	}
+	if data := clickOn(&discordgo.Message{}); len(data.Components) != 0 {
+		t.Errorf("expected no share button on a message without one, got %+v", data.Components)
+	}
+}
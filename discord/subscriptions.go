@@ -0,0 +1,181 @@
+package discord
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/brensch/assistant/db"
+	"github.com/bwmarrin/discordgo"
+)
+
+// subscriptionStore persists which users have opted in to direct messages from which targets.
+type subscriptionStore struct {
+	dbClient *db.Client
+}
+
+// init creates the subscriptions table if it doesn't exist.
+func (s *subscriptionStore) init() error {
+	_, err := s.dbClient.Conn().Exec(`
+	CREATE TABLE IF NOT EXISTS discord_subscriptions (
+		target TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		subscribed_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+		PRIMARY KEY (target, user_id)
+	)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create discord_subscriptions table: %w", err)
+	}
+	return nil
+}
+
+// subscribe opts a user in to a target. Subscribing twice has no effect.
+func (s *subscriptionStore) subscribe(target, userID string) error {
+	_, err := s.dbClient.Conn().Exec(
+		"INSERT OR IGNORE INTO discord_subscriptions (target, user_id) VALUES (?, ?)", target, userID)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe %s to %s: %w", userID, target, err)
+	}
+	return nil
+}
+
+// unsubscribe opts a user out of a target, reporting whether they were subscribed.
+func (s *subscriptionStore) unsubscribe(target, userID string) (bool, error) {
+	res, err := s.dbClient.Conn().Exec(
+		"DELETE FROM discord_subscriptions WHERE target = ? AND user_id = ?", target, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to unsubscribe %s from %s: %w", userID, target, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to unsubscribe %s from %s: %w", userID, target, err)
+	}
+	return n > 0, nil
+}
+
+// subscribers returns the IDs of the users subscribed to a target.
+func (s *subscriptionStore) subscribers(target string) ([]string, error) {
+	return s.query("SELECT user_id FROM discord_subscriptions WHERE target = ? ORDER BY user_id", target)
+}
+
+// subscriptions returns the names of the targets a user is subscribed to.
+func (s *subscriptionStore) subscriptions(userID string) ([]string, error) {
+	return s.query("SELECT target FROM discord_subscriptions WHERE user_id = ? ORDER BY target", userID)
+}
+
+// query runs a query returning a single text column.
+func (s *subscriptionStore) query(query string, arg string) ([]string, error) {
+	rows, err := s.dbClient.Conn().Query(query, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to scan subscription: %w", err)
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+// WithSubscriptions lets users opt in to direct messages from subscribable targets with a
+// /subscriptions command. Subscriptions are stored in the given database.
+func WithSubscriptions(dbClient *db.Client, opts ...FunctionOption) BotOption {
+	return func(b *Bot) {
+		b.subscriptions = &subscriptionStore{dbClient: dbClient}
+		// Clip so the caller's slice of functions is never written to.
+		b.functions = append(slices.Clip(b.functions), b.subscriptionsCommand(opts...))
+	}
+}
+
+// subscriptionRequest names the target to subscribe to or unsubscribe from.
+type subscriptionRequest struct {
+	Target string `discord:"autocomplete,description:Notifications to receive by direct message"`
+}
+
+// subscriptionsCommand builds the /subscriptions command group.
+func (b *Bot) subscriptionsCommand(opts ...FunctionOption) BotFunctionI {
+	// Subscriptions are personal, so managing them from a DM is the natural place.
+	opts = append([]FunctionOption{
+		WithDMPermission(true),
+		WithDescription("Get notifications by direct message"),
+		WithCategory("Settings"),
+		WithExamples("/subscriptions subscribe target:zaps", "/subscriptions list"),
+	}, opts...)
+	complete := AutocompleteFunc(b.completeSubscribableTargets)
+	return NewBotFunctionGroup("subscriptions", []BotFunctionI{
+		NewBotFunctionWithContext("list", b.handleListSubscriptions, nil, WithDescription("List your subscriptions")),
+		NewBotFunctionWithContext("subscribe", b.handleSubscribe, complete, WithDescription("Subscribe to a target")),
+		NewBotFunctionWithContext("unsubscribe", b.handleUnsubscribe, complete, WithDescription("Unsubscribe from a target")),
+	}, opts...)
+}
+
+// completeSubscribableTargets suggests the names of targets users can subscribe to.
+func (b *Bot) completeSubscribableTargets(option, input string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	targets := b.Targets()
+	for _, name := range slices.Sorted(maps.Keys(targets)) {
+		if targets[name].Subscribable && strings.HasPrefix(name, input) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+		}
+	}
+	return choices, nil
+}
+
+// handleListSubscriptions shows the targets the user is subscribed to.
+func (b *Bot) handleListSubscriptions(inv *Invocation, _ struct{}) (*Response, error) {
+	targets, err := b.subscriptions.subscriptions(inv.UserID())
+	if err != nil {
+		return nil, err
+	}
+	content := "You aren't subscribed to anything."
+	if len(targets) > 0 {
+		content = "You're subscribed to: " + strings.Join(targets, ", ")
+	}
+	return &Response{
+		Content:   content,
+		Ephemeral: true,
+	}, nil
+}
+
+// handleSubscribe opts the user in to direct messages from a target, if its policy allows them.
+func (b *Bot) handleSubscribe(inv *Invocation, req subscriptionRequest) (*Response, error) {
+	_, target, ok := b.lookupTarget(req.Target)
+	if !ok || !target.Subscribable {
+		return nil, UserErrorf("%s can't be subscribed to.", req.Target)
+	}
+	if reason := target.SubscribePolicy.check(inv, b.guildOwner(inv.GuildID, target.SubscribePolicy)); reason != "" {
+		return nil, UserErrorf("You can't subscribe to %s: %s", req.Target, reason)
+	}
+
+	err := b.subscriptions.subscribe(req.Target, inv.UserID())
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		Content:   fmt.Sprintf("Subscribed to **%s**. Notifications will arrive as direct messages.", req.Target),
+		Ephemeral: true,
+	}, nil
+}
+
+// handleUnsubscribe opts the user out of a target.
+func (b *Bot) handleUnsubscribe(inv *Invocation, req subscriptionRequest) (*Response, error) {
+	removed, err := b.subscriptions.unsubscribe(req.Target, inv.UserID())
+	if err != nil {
+		return nil, err
+	}
+	content := fmt.Sprintf("Unsubscribed from **%s**.", req.Target)
+	if !removed {
+		content = fmt.Sprintf("You weren't subscribed to **%s**.", req.Target)
+	}
+	return &Response{
+		Content:   content,
+		Ephemeral: true,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package discord
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/brensch/assistant/db"
+)
+
+func TestSubscriptionStore(t *testing.T) {
+	dbClient, err := db.NewClient(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbClient.Stop()
+
+	store := &subscriptionStore{dbClient: dbClient}
+	if err := store.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sub := range [][2]string{{"zaps", "alice"}, {"zaps", "bob"}, {"zaps", "alice"}, {"weather", "alice"}} {
+		if err := store.subscribe(sub[0], sub[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	subscribers, err := store.subscribers("zaps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(subscribers, []string{"alice", "bob"}) {
+		t.Errorf("unexpected subscribers %v", subscribers)
+	}
+
+	removed, err := store.unsubscribe("zaps", "alice")
+	if err != nil || !removed {
+		t.Fatalf("expected alice to be unsubscribed, got %v, %v", removed, err)
+	}
+	if removed, _ := store.unsubscribe("zaps", "alice"); removed {
+		t.Error("unsubscribing twice should report nothing removed")
+	}
+
+	targets, err := store.subscriptions("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(targets, []string{"weather"}) {
+		t.Errorf("unexpected subscriptions %v", targets)
+	}
+}
@@ -0,0 +1,219 @@
+package discord
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// DefaultTarget is the notification target used when none is named. Until it is configured,
+// it posts to the first text channel of every guild the bot is in.
+const DefaultTarget = "default"
+
+// Target is a named destination for notifications. A message sent to a target goes to each of
+// its channels and to each of its users as a direct message.
+type Target struct {
+	// Channels are the IDs of the channels or threads to post in.
+	Channels []string
+	// Users are the IDs of the users to send direct messages to.
+	Users []string
+	// Subscribable lets users opt in to direct messages from the target themselves, when
+	// subscriptions are enabled with WithSubscriptions.
+	Subscribable bool
+	// SubscribePolicy restricts who can subscribe, e.g. to keep personal data private.
+	SubscribePolicy Policy
+}
+
+// WithTargets configures named notification targets, e.g. from the config file.
+func WithTargets(targets map[string]Target) BotOption {
+	return func(b *Bot) {
+		for name, target := range targets {
+			b.targets[name] = target
+		}
+	}
+}
+
+// WithTargetsCommand adds a /targets command for listing and changing notification targets
+// while the bot is running. It requires the Manage Server permission unless opts say otherwise.
+// Changes made through it are not saved and are lost on restart.
+//
+// Targets are shared by every guild the bot is in, so the Manage Server permission of any one guild
+// is only enough for bots that stay in guilds with the same admins. Other bots should limit the
+// command to their operators with WithPolicy.
+func WithTargetsCommand(opts ...FunctionOption) BotOption {
+	return func(b *Bot) {
+		// Clip so the caller's slice of functions is never written to.
+		b.functions = append(slices.Clip(b.functions), b.targetsCommand(opts...))
+	}
+}
+
+// SetTarget adds or replaces a notification target.
+func (b *Bot) SetTarget(name string, target Target) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.targets[name] = target
+}
+
+// RemoveTarget deletes a notification target. Removing the default target makes it fall
+// back to the first text channel of every guild again.
+func (b *Bot) RemoveTarget(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.targets, name)
+}
+
+// removeTargetChannels takes the channels of a guild the bot has left out of every target, logging
+// each change. Targets left with nowhere to send are removed, so the default target falls back to
+// the first text channel of every guild again and sending to others reports an unknown target.
+func (b *Bot) removeTargetChannels(guildID string, channels []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name, target := range b.targets {
+		kept := slices.DeleteFunc(slices.Clone(target.Channels), func(id string) bool {
+			return slices.Contains(channels, id)
+		})
+		if len(kept) == len(target.Channels) {
+			continue
+		}
+		if len(kept) == 0 && len(target.Users) == 0 && !target.Subscribable {
+			delete(b.targets, name)
+			slog.Warn("removed target that only sent to a guild the bot left", "target", name, "guild", guildID)
+			continue
+		}
+		slog.Warn("removed channels of a guild the bot left from target", "target", name, "guild", guildID,
+			"removed", len(target.Channels)-len(kept))
+		target.Channels = kept
+		b.targets[name] = target
+	}
+}
+
+// Targets returns a copy of the configured notification targets.
+func (b *Bot) Targets() map[string]Target {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return maps.Clone(b.targets)
+}
+
+// lookupTarget returns the target with the given name, treating an empty name as DefaultTarget.
+func (b *Bot) lookupTarget(name string) (string, Target, bool) {
+	if name == "" {
+		name = DefaultTarget
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	target, ok := b.targets[name]
+	return name, target, ok
+}
+
+// targetAddRequest adds a channel, thread or user to a target.
+type targetAddRequest struct {
+	Name    string             `discord:"autocomplete,description:Name of the target (e.g. finance)"`
+	Channel *discordgo.Channel `discord:"optional,channel_types:text|news|public_thread|private_thread|news_thread,description:Channel or thread to post in"`
+	User    *discordgo.User    `discord:"optional,description:User to send direct messages to"`
+}
+
+// targetRemoveRequest removes a target.
+type targetRemoveRequest struct {
+	Name string `discord:"autocomplete,description:Name of the target to remove"`
+}
+
+// targetsCommand builds the /targets command group.
+func (b *Bot) targetsCommand(opts ...FunctionOption) BotFunctionI {
+	opts = append([]FunctionOption{
+		WithPolicy(Policy{Permissions: discordgo.PermissionManageServer}),
+		WithDescription("Manage where notifications are sent"),
+		WithCategory("Settings"),
+		WithExamples("/targets add name:finance channel:#money", "/targets list"),
+	}, opts...)
+	complete := AutocompleteFunc(b.completeTargets)
+	return NewBotFunctionGroup("targets", []BotFunctionI{
+		NewBotFunction("list", b.handleListTargets, nil, WithDescription("List the notification targets")),
+		NewBotFunction("add", b.handleAddTarget, complete, WithDescription("Add a channel or user to a target")),
+		NewBotFunction("remove", b.handleRemoveTarget, complete, WithDescription("Remove a target")),
+	}, opts...)
+}
+
+// completeTargets suggests the names of existing targets.
+func (b *Bot) completeTargets(option, input string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	for _, name := range slices.Sorted(maps.Keys(b.Targets())) {
+		if strings.HasPrefix(name, input) {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+		}
+	}
+	return choices, nil
+}
+
+// handleListTargets shows every target and where it sends to.
+func (b *Bot) handleListTargets(struct{}) (*Response, error) {
+	targets := b.Targets()
+	if len(targets) == 0 {
+		return &Response{
+			Content:   "No targets configured. Notifications go to the first text channel of each server.",
+			Ephemeral: true,
+		}, nil
+	}
+
+	var lines []string
+	for _, name := range slices.Sorted(maps.Keys(targets)) {
+		lines = append(lines, fmt.Sprintf("**%s**: %s", name, describeTarget(targets[name])))
+	}
+	return &Response{
+		Content:   strings.Join(lines, "\n"),
+		Ephemeral: true,
+	}, nil
+}
+
+// handleAddTarget adds a channel or user to a target, creating the target if needed.
+func (b *Bot) handleAddTarget(req targetAddRequest) (*Response, error) {
+	if req.Channel == nil && req.User == nil {
+		return nil, UserErrorf("Give a channel or a user to add to %s.", req.Name)
+	}
+
+	b.mu.Lock()
+	target := b.targets[req.Name]
+	if req.Channel != nil && !slices.Contains(target.Channels, req.Channel.ID) {
+		target.Channels = append(slices.Clone(target.Channels), req.Channel.ID)
+	}
+	if req.User != nil && !slices.Contains(target.Users, req.User.ID) {
+		target.Users = append(slices.Clone(target.Users), req.User.ID)
+	}
+	b.targets[req.Name] = target
+	b.mu.Unlock()
+
+	return &Response{
+		Content:   fmt.Sprintf("**%s** now sends to %s", req.Name, describeTarget(target)),
+		Ephemeral: true,
+	}, nil
+}
+
+// handleRemoveTarget deletes a target.
+func (b *Bot) handleRemoveTarget(req targetRemoveRequest) (*Response, error) {
+	if _, _, ok := b.lookupTarget(req.Name); !ok {
+		return nil, UserErrorf("Unknown target %s.", req.Name)
+	}
+	b.RemoveTarget(req.Name)
+	return &Response{
+		Content:   fmt.Sprintf("Removed **%s**", req.Name),
+		Ephemeral: true,
+	}, nil
+}
+
+// describeTarget lists a target's destinations as mentions.
+func describeTarget(target Target) string {
+	var mentions []string
+	for _, id := range target.Channels {
+		mentions = append(mentions, "<#"+id+">")
+	}
+	for _, id := range target.Users {
+		mentions = append(mentions, "<@"+id+">")
+	}
+	if len(mentions) == 0 {
+		return "nowhere"
+	}
+	return strings.Join(mentions, ", ")
+}
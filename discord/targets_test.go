@@ -0,0 +1,54 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestTargetsCommand(t *testing.T) {
+	b := &Bot{targets: make(map[string]Target)}
+	cmd := b.targetsCommand()
+	if _, err := cmd.GetCommand(); err != nil {
+		t.Fatal(err)
+	}
+
+	add := &discordgo.ApplicationCommandInteractionData{
+		Name: "targets",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+			Name: "add",
+			Type: discordgo.ApplicationCommandOptionSubCommand,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "name", Type: discordgo.ApplicationCommandOptionString, Value: "finance"},
+				{Name: "channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "42"},
+			},
+		}},
+		Resolved: &discordgo.ApplicationCommandInteractionDataResolved{
+			Channels: map[string]*discordgo.Channel{"42": {ID: "42"}},
+		},
+	}
+	if _, err := cmd.HandleInteraction(&Invocation{}, add); err != nil {
+		t.Fatal(err)
+	}
+
+	target, ok := b.Targets()["finance"]
+	if !ok || len(target.Channels) != 1 || target.Channels[0] != "42" {
+		t.Fatalf("target not added: %+v", target)
+	}
+
+	list := &discordgo.ApplicationCommandInteractionData{
+		Name: "targets",
+		Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+			Name: "list",
+			Type: discordgo.ApplicationCommandOptionSubCommand,
+		}},
+	}
+	resp, err := cmd.HandleInteraction(&Invocation{}, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp.Content, "**finance**: <#42>") {
+		t.Errorf("unexpected listing %q", resp.Content)
+	}
+}
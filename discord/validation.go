@@ -0,0 +1,182 @@
+package discord
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultTimeLayout is used for time.Time fields without a "layout" tag.
+const defaultTimeLayout = time.DateOnly
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// FieldError describes why the value given for a single option was rejected.
+type FieldError struct {
+	// Field is the option name, i.e. the lower-cased field name.
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when one or more options fail validation. Each invalid
+// option is listed separately in the error shown to the user.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var parts []string
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return "invalid options: " + strings.Join(parts, "; ")
+}
+
+// isTextType reports whether values of t are entered as text and parsed by parseText
+// rather than decoded by kind: times, durations and types implementing encoding.TextUnmarshaler.
+func isTextType(t reflect.Type) bool {
+	return t == timeType || t == durationType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// parseText parses s into a value of a text type, using the "layout" tag for times.
+func parseText(s string, t reflect.Type, tags map[string]string) (reflect.Value, error) {
+	switch t {
+	case timeType:
+		layout := timeLayout(tags)
+		parsed, err := time.Parse(layout, s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("must be a date like %s", layout)
+		}
+		return reflect.ValueOf(parsed), nil
+	case durationType:
+		parsed, err := time.ParseDuration(s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("must be a duration like 1h30m")
+		}
+		return reflect.ValueOf(parsed), nil
+	}
+
+	v := reflect.New(t)
+	err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	return v.Elem(), nil
+}
+
+// formatText is the inverse of parseText, used to encode component state.
+func formatText(v reflect.Value, tags map[string]string) (string, error) {
+	switch v.Type() {
+	case timeType:
+		return v.Interface().(time.Time).Format(timeLayout(tags)), nil
+	case durationType:
+		return v.Interface().(time.Duration).String(), nil
+	}
+	if marshaler, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		return string(text), err
+	}
+	if v.Kind() == reflect.String {
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("%s does not implement encoding.TextMarshaler", v.Type())
+}
+
+// timeLayout returns the layout for a time field.
+func timeLayout(tags map[string]string) string {
+	if layout, ok := tags["layout"]; ok && layout != "" {
+		return layout
+	}
+	return defaultTimeLayout
+}
+
+// checkValidationTags rejects validation tags that can't apply to a field, so mistakes
+// are caught when commands are built rather than when they're run.
+func checkValidationTags(field reflect.StructField, tags map[string]string) error {
+	name := strings.ToLower(field.Name)
+	if pattern, ok := tags["regex"]; ok {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("option %s: invalid regex: %w", name, err)
+		}
+	}
+	if _, ok := tags["layout"]; ok && field.Type != timeType {
+		return fmt.Errorf("option %s: layout only applies to time.Time", name)
+	}
+	return nil
+}
+
+// validateText checks a raw text value against the "regex" and "enum" tags, returning
+// a message for the user if it fails.
+func validateText(s string, tags map[string]string) string {
+	if enum, ok := tags["enum"]; ok {
+		// Allowed values are separated by pipes, e.g. "low|medium|high".
+		allowed := strings.Split(enum, "|")
+		if !slices.Contains(allowed, s) {
+			return "must be one of " + strings.Join(allowed, ", ")
+		}
+	}
+	if pattern, ok := tags["regex"]; ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil || !re.MatchString(s) {
+			return "must match " + pattern
+		}
+	}
+	return ""
+}
+
+// prepareValues validates the raw option values against the request struct's tags and parses
+// text values into times, durations and TextUnmarshaler types, so they can be decoded as-is.
+// Every invalid option is reported in a single ValidationError.
+func prepareValues(values map[string]interface{}, req interface{}) (map[string]interface{}, error) {
+	t := reflect.TypeOf(req)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("request is not a struct")
+	}
+
+	prepared := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		prepared[k] = v
+	}
+
+	var verr ValidationError
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.ToLower(field.Name)
+		s, ok := prepared[name].(string)
+		if !ok {
+			continue
+		}
+		tags := parseDiscordTag(field.Tag.Get("discord"))
+
+		if msg := validateText(s, tags); msg != "" {
+			verr.Fields = append(verr.Fields, FieldError{Field: name, Message: msg})
+			continue
+		}
+		if !isTextType(field.Type) {
+			continue
+		}
+		parsed, err := parseText(s, field.Type, tags)
+		if err != nil {
+			verr.Fields = append(verr.Fields, FieldError{Field: name, Message: err.Error()})
+			continue
+		}
+		prepared[name] = parsed.Interface()
+	}
+
+	if len(verr.Fields) > 0 {
+		return nil, &verr
+	}
+	return prepared, nil
+}
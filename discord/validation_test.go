@@ -0,0 +1,101 @@
+package discord
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type priority string
+
+func (p *priority) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low", "high":
+		*p = priority(text)
+		return nil
+	}
+	return fmt.Errorf("unknown priority %q", text)
+}
+
+type typedRequest struct {
+	Date     time.Time     `discord:"layout:2006/01/02"`
+	Every    time.Duration `discord:"optional,default:1h"`
+	Priority priority      `discord:"optional"`
+	Code     string        `discord:"optional,regex:^[A-Z]{3}$"`
+	Size     string        `discord:"optional,enum:s|m|l"`
+}
+
+func TestDecodeTypedRequest(t *testing.T) {
+	var req typedRequest
+	err := decodeRequest(map[string]interface{}{
+		"date":     "2025/03/14",
+		"priority": "high",
+		"code":     "ABC",
+		"size":     "m",
+	}, &req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !req.Date.Equal(time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected date %v", req.Date)
+	}
+	if req.Every != time.Hour {
+		t.Errorf("expected default of 1h, got %v", req.Every)
+	}
+	if req.Priority != "high" {
+		t.Errorf("unexpected priority %q", req.Priority)
+	}
+
+	options, err := structToCommandOptions(typedRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opt := range options {
+		if opt.Type != discordgo.ApplicationCommandOptionString {
+			t.Errorf("option %s: expected a string option, got %s", opt.Name, opt.Type)
+		}
+	}
+
+	id, err := CustomID("typed", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, values, err := parseCustomID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded typedRequest
+	if err := decodeRequest(values, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Date.Equal(req.Date) || decoded.Every != req.Every {
+		t.Errorf("state did not round trip: %+v", decoded)
+	}
+}
+
+func TestDecodeValidationErrors(t *testing.T) {
+	var req typedRequest
+	err := decodeRequest(map[string]interface{}{
+		"date":     "14/03/2025",
+		"every":    "soon",
+		"priority": "urgent",
+		"code":     "abc",
+		"size":     "xl",
+	}, &req)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if len(verr.Fields) != 5 {
+		t.Fatalf("expected every field to fail, got %v", verr.Fields)
+	}
+
+	embed := reportError("invalid request", err).Embeds[0]
+	if len(embed.Fields) != 5 || embed.Fields[0].Name != "date" {
+		t.Errorf("expected one embed field per invalid option, got %+v", embed.Fields)
+	}
+}
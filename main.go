@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"time"
 
+	"github.com/brensch/assistant/api"
+	"github.com/brensch/assistant/cli"
 	"github.com/brensch/assistant/config"
 	"github.com/brensch/assistant/db"
 	"github.com/brensch/assistant/derozap"
@@ -16,13 +21,21 @@ import (
 func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Running "assistant repl" or "assistant invoke ..." runs commands in the terminal instead of
+	// connecting to Discord, so only warnings are logged, out of the way of the output.
+	cliMode := cli.IsCommand(os.Args[1:])
+	logOutput, logLevel := os.Stdout, slog.LevelDebug
+	if cliMode {
+		logOutput, logLevel = os.Stderr, slog.LevelWarn
+	}
+
 	// Configure pretty colored logging with tint.
 	opts := log.PrettyHandlerOptions{
 		SlogOpts: slog.HandlerOptions{
-			Level: slog.LevelDebug,
+			Level: logLevel,
 		},
 	}
-	handler := log.NewPrettyHandler(os.Stdout, opts)
+	handler := log.NewPrettyHandler(logOutput, opts)
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
@@ -60,12 +73,12 @@ func main() {
 
 	// Configure and start the bot using config values
 	discordCfg := discord.BotConfig{
-		AppID:    cfg.Discord.AppID,
-		BotToken: cfg.Discord.BotToken,
+		AppID:          cfg.Discord.AppID,
+		BotToken:       cfg.Discord.BotToken,
+		GlobalCommands: cfg.Discord.GlobalCommands,
+		BulkOverwrite:  cfg.Discord.BulkOverwrite,
 	}
 
-	slog.Info("Initializing bot", "app_id", discordCfg.AppID, "token_prefix", discordCfg.BotToken[:5]+"...")
-
 	// Use config values for DERO client
 	deroClient, err := derozap.NewClient(cfg.Dero.Username, cfg.Dero.Password, dbClient)
 	if err != nil {
@@ -73,24 +86,87 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Zap data is personal, so only the configured users and roles can see it.
+	deroPolicy := discord.Policy{
+		AllowedUsers: cfg.Dero.AllowedUsers,
+		AllowedRoles: cfg.Dero.AllowedRoles,
+	}
+
 	// Create a slice of bot functions using generics.
 	functions := []discord.BotFunctionI{
-		// The autocomplete parameter is nil here.
-		deroClient.DiscordFunctionRetrieveZaps(),
+		deroClient.DiscordFunctionRetrieveZaps(discord.WithPolicy(deroPolicy)),
 	}
 
 	// Define scheduled tasks
+	var zapCheckOpts []discord.ScheduleOption
+	if cfg.Dero.NotificationTarget != "" {
+		zapCheckOpts = append(zapCheckOpts, discord.WithTarget(cfg.Dero.NotificationTarget))
+	}
 	schedules := []discord.BotScheduleI{
-		deroClient.DiscordScheduleZapCheck("0 0 * * * *"),
+		deroClient.DiscordScheduleZapCheck("0 0 * * * *", zapCheckOpts...),
+		deroClient.DiscordScheduleMonthlyReport("0 0 9 1 * *", zapCheckOpts...),
+	}
+
+	// Map the configured notification targets.
+	targets := make(map[string]discord.Target)
+	for name, target := range cfg.Discord.Targets {
+		t := discord.Target{
+			Channels:     target.Channels,
+			Users:        target.Users,
+			Subscribable: target.Subscribable,
+		}
+		// Subscribing to zap notifications is limited to those who can see zap data.
+		if name == cfg.Dero.NotificationTarget {
+			t.SubscribePolicy = deroPolicy
+		}
+		targets[name] = t
+	}
+
+	botOpts := []discord.BotOption{
+		discord.WithComponents(deroClient.DiscordComponentRefreshZaps(discord.WithPolicy(deroPolicy))),
+		discord.WithTargets(targets),
+		discord.WithSubscriptions(dbClient),
+	}
+
+	// Targets are shared by every server the bot is in, so only its operators can change them.
+	if len(cfg.Discord.Operators) > 0 {
+		botOpts = append(botOpts, discord.WithTargetsCommand(discord.WithPolicy(discord.Policy{
+			AllowedUsers: cfg.Discord.Operators,
+		})))
+	}
+
+	if cliMode {
+		err = runCLI(ctx, functions, botOpts)
+		dbClient.Stop()
+		if err != nil {
+			slog.Error("command failed", "error", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Create the bot, providing the configuration and list of functions.
-	bot, err := discord.NewBot(discordCfg, functions, schedules)
+	slog.Info("Initializing bot", "app_id", discordCfg.AppID, "token_prefix", discordCfg.BotToken[:5]+"...")
+
+	// Create the bot, providing the configuration, list of functions and component handlers.
+	bot, err := discord.NewBot(discordCfg, functions, schedules, botOpts...)
 	if err != nil {
 		slog.Error("Failed to create bot", "error", err)
 		os.Exit(1)
 	}
 
+	// Log every command with its duration and outcome.
+	bot.Use(discord.LogInvocations())
+
+	// Serve the commands over HTTP too, if configured.
+	var apiServer *http.Server
+	if cfg.API.Address != "" {
+		apiServer, err = startAPI(cfg, bot)
+		if err != nil {
+			slog.Error("Failed to start API", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	// Log successful startup.
 	slog.Info("Bot is now running")
 
@@ -99,9 +175,15 @@ func main() {
 	signal.Notify(stop, os.Interrupt)
 	<-stop
 
-	err = dbClient.Stop()
-	if err != nil {
-		slog.Error("failed to stop client", "error", err)
+	// Stop taking API requests first, giving those in flight a while to finish, then shut down the
+	// bot, and only then the database the commands use.
+	if apiServer != nil {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 30*time.Second)
+		err = apiServer.Shutdown(shutdownCtx)
+		cancelShutdown()
+		if err != nil {
+			slog.Error("failed to stop API", "error", err)
+		}
 	}
 
 	// Shut down the bot.
@@ -111,5 +193,57 @@ func main() {
 		slog.Error("Error during shutdown", "error", err)
 	}
 
+	err = dbClient.Stop()
+	if err != nil {
+		slog.Error("failed to stop client", "error", err)
+	}
+
 	cancel()
 }
+
+// runCLI runs the bot's commands in the terminal instead of on Discord.
+func runCLI(ctx context.Context, functions []discord.BotFunctionI, botOpts []discord.BotOption) error {
+	bot, err := discord.NewLocalBot(functions, botOpts...)
+	if err != nil {
+		return err
+	}
+	defer bot.Close()
+
+	bot.Use(discord.LogInvocations())
+	return cli.Run(ctx, bot, os.Args[1:], os.Stdin, os.Stdout)
+}
+
+// startAPI serves the bot's commands as a JSON API on the configured address.
+func startAPI(cfg *config.AppConfig, bot *discord.Bot) (*http.Server, error) {
+	var tokens []api.Token
+	for _, t := range cfg.API.Tokens {
+		tokens = append(tokens, api.Token{
+			Token:   t.Token,
+			UserID:  t.UserID,
+			GuildID: t.GuildID,
+			Roles:   t.Roles,
+		})
+	}
+	handler, err := api.NewServer(bot, api.WithTokens(tokens...))
+	if err != nil {
+		return nil, err
+	}
+
+	// Slow or idle clients are cut off so they can't hold connections open. There's no write timeout,
+	// as commands such as scrapes can take a while before the response is written.
+	server := &http.Server{
+		Addr:              cfg.API.Address,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+	}
+	go func() {
+		slog.Info("API listening", "address", cfg.API.Address)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("API stopped", "error", err)
+		}
+	}()
+	return server, nil
+}